@@ -22,13 +22,95 @@
 //     used for rule generation.
 //   - `min-confidence`: minimum confidence for rule generation.
 //   - `min-lift`: minimum lift for rule generation.
+//   - `negative-output`: optional path to file to write negative rules to, of
+//     the form "X => !(Y)", "!(X) => Y" and "!(X) => !(Y)".
+//   - `min-negative-confidence`: minimum confidence for negative rules.
+//   - `min-negative-lift`: minimum lift for negative rules.
+//   - `weighted`: optional; if specified the first column of every input line
+//     is the number of times that transaction occurs.
+//   - `algorithm`: optional; frequent itemset mining algorithm, one of
+//     fpgrowth, apriori, eclat, declat or bitset. By default FP-growth is
+//     used, or bitset if the dataset is dense.
+//   - `parallelism`: optional; number of goroutines used to read the input and
+//     generate rules, defaults to the number of CPUs.
+//   - `sample`: optional; if specified, frequent itemsets are found by mining a
+//     random sample of this many transactions at `sample-min-support`, and
+//     verifying the result with a pass over the dataset, rather than by
+//     building an FP-tree of the whole dataset.
+//   - `sample-min-support`: lowered support threshold for mining the sample,
+//     defaults to 0.8 times `min-support`.
+//   - `target-itemsets`: optional; instead of passing `min-support`, choose
+//     the lowest min-support generating at most this many itemsets. An
+//     FP-tree is mined at a binary search of supports, stopping each attempt
+//     once it exceeds the target. With `sample`, the sample is searched, so
+//     the number of itemsets is an estimate. The chosen support is logged.
+//   - `target-memory`: optional; like `target-itemsets`, but limits the
+//     estimated megabytes of memory used by the itemsets.
+//   - `suggest-timeout`: optional; time limit, such as 30s, for choosing
+//     min-support, after which the lowest support found so far is used, or
+//     arm fails if none has been found.
+//   - `no-progress`: optional; if specified, the progress of each phase isn't
+//     reported. By default a progress bar is drawn if the output is a
+//     terminal, and otherwise progress is logged every 20 seconds.
+//   - `memory-budget`: optional; megabytes of itemsets to hold in memory. Beyond
+//     this, itemsets are spilled to sorted files on disk, and rules are
+//     written as they're generated. Can't be combined with `sample`,
+//     `algorithm` or `negative-output`.
+//   - `spill-dir`: optional; directory in which to write spilled itemsets,
+//     defaults to the system temporary directory.
+//   - `checkpoint`: optional; directory in which to save the first pass, and
+//     the itemsets generated from each frequent item as it's mined.
+//   - `resume`: optional; if specified, the first pass is restored from
+//     `checkpoint` rather than reading `input`, and items already mined are
+//     skipped. The output is the same as if arm hadn't been stopped.
+//   - `report`: optional; path of a file to write a JSON report of the run to,
+//     with its status (completed, interrupted or failed), each phase's
+//     duration, the peak heap size, the numbers of transactions, items and
+//     FP-tree nodes, itemsets by length and rules.
+//   - `metrics-addr`: optional; address such as localhost:9090 on which the
+//     same metrics are served at /metrics in Prometheus text format while arm
+//     runs.
+//   - `log-level`: optional; minimum level of log records, one of debug,
+//     info, warn or error. Defaults to info; debug adds records of each
+//     phase's internals, such as the size of the FP-tree.
+//   - `log-format`: optional; text or json, defaults to text. Records are
+//     structured, with attributes such as phase, duration and counts.
+//
+// Interrupting arm with Ctrl-C stops it, and reports which phase was
+// interrupted and how long it had run for. The run report is still written.
+//
+// Subcommands, which also accept `--log-level` and `--log-format`:
+//
+//   - `arm utility --input $csv --profits $csv --output $csv --min-utility $u`
+//     generates high utility itemsets. Input transactions are comma separated
+//     item:quantity pairs, and profits is a CSV of item,unit-profit pairs.
+//   - `arm sequences --input $seq --output $csv --min-support $s ...` generates
+//     sequential patterns and rules. Each input line is a sequence of itemsets
+//     separated by ';'. Accepts `--patterns`, `--min-confidence`,
+//     `--min-lift`, `--max-gap` and `--max-window`.
+//   - `arm diff --baseline $csv --comparison $csv --output $csv ...` reports
+//     emerging patterns, itemsets whose support changed between the datasets
+//     by `--min-growth-rate` or `--min-difference`, and optionally writes how
+//     rules' confidence and lift changed to `--rules`.
+//   - `arm classify --train $csv --classes a,b --min-support $s ...` builds a
+//     CBA rule classifier from class association rules, and reports its
+//     accuracy and confusion matrix on `--holdout`. Each class's minimum
+//     support is `--min-support` scaled by the class's support, unless
+//     `--scale-support=false`.
+//   - `arm stats --input $csv` reports the number of transactions and items,
+//     the distribution of transaction lengths, the most frequent items, the
+//     long tail, and how many items survive each of `--supports`, to help
+//     choose `min-support`. Accepts `--top`, `--format` and `--output`.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"time"
 
 	"github.com/cpearce/arm-go/fpgrowth"
@@ -41,19 +123,135 @@ func check(e error) {
 	}
 }
 
+// logInterrupted returns err, first logging how long phase ran for along with
+// attrs if err is because arm was interrupted.
+func logInterrupted(err error, phase fpgrowth.Phase, start time.Time, attrs ...any) error {
+	if errors.Is(err, context.Canceled) {
+		attrs = append([]any{"phase", phase, "duration", time.Since(start)}, attrs...)
+		slog.Error("interrupted", attrs...)
+	}
+	return err
+}
+
+// runStatus returns the status of a run which returned err.
+func runStatus(err error) string {
+	switch {
+	case err == nil:
+		return "completed"
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	default:
+		return "failed"
+	}
+}
+
+var subcommands = map[string]func(args []string){
+	"utility":   utility,
+	"sequences": sequences,
+	"diff":      diff,
+	"classify":  classify,
+	"stats":     stats,
+}
+
+// suggestMinSupport returns the lowest min-support whose itemsets are within
+// budget, searching for at most timeout if it's non-zero.
+func suggestMinSupport(
+	interrupt context.Context,
+	logger *slog.Logger,
+	run *runReport,
+	ctx fpgrowth.Context,
+	budget fpgrowth.ItemsetBudget,
+	timeout time.Duration,
+) (float64, error) {
+	logger.Info(
+		"choosing min support",
+		"target_itemsets", budget.Itemsets,
+		"target_bytes", budget.Bytes,
+		"timeout", timeout,
+	)
+	start := time.Now()
+	c := interrupt
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		c, cancel = context.WithTimeout(interrupt, timeout)
+		defer cancel()
+	}
+	suggestion, err := ctx.SuggestMinSupport(c, budget)
+	if err == nil {
+		err = interrupt.Err()
+	}
+	if err := logInterrupted(err, "choosing min support", start); err != nil {
+		return 0, err
+	}
+	run.addPhase("choosing min support", time.Since(start))
+	logger.Info(
+		"chose min support",
+		"min_support", suggestion.MinSupport,
+		"itemsets", suggestion.Itemsets,
+		"bytes", suggestion.Bytes,
+		"probes", suggestion.Probes,
+		"sampled", suggestion.Sampled,
+		"complete", suggestion.Complete,
+		"duration", time.Since(start),
+	)
+	return suggestion.MinSupport, nil
+}
+
 func main() {
-	log.Println("Association Rule Mining - in Go via FPGrowth")
+	if len(os.Args) > 1 {
+		if subcommand, found := subcommands[os.Args[1]]; found {
+			subcommand(os.Args[2:])
+			return
+		}
+	}
+	if err := mineRules(); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Error("failed", "error", err)
+		}
+		os.Exit(-1)
+	}
+}
 
+// mineRules implements arm's main command, generating association rules. The
+// run report, if requested, is written before it returns, with the run's
+// status.
+func mineRules() (err error) {
 	input := flag.String("input", "", "Input dataset in CSV format.")
 	output := flag.String("output", "", "File path in which to store output rules. Format: antecedent -> consequent, confidence, lift, support.")
 	minSupport := flag.Float64("min-support", 0, "Minimum itemset support threshold, in range [0,1].")
 	minConfidence := flag.Float64("min-confidence", 0, "Minimum rule confidence threshold, in range [0,1].")
 	minLift := flag.Float64("min-lift", 1, "Minimum rule lift confidence threshold, in range [1,∞] (optional)")
 	itemsetsPath := flag.String("itemsets", "", "File path in which to store generated itemsets (optional).")
+	negativeOutput := flag.String("negative-output", "", "File path in which to store negative rules (optional).")
+	minNegativeConfidence := flag.Float64("min-negative-confidence", 0, "Minimum negative rule confidence threshold, in range [0,1] (optional).")
+	minNegativeLift := flag.Float64("min-negative-lift", 1, "Minimum negative rule lift threshold, in range [1,∞] (optional).")
+	weighted := flag.Bool("weighted", false, "First column of each input line is the transaction's count (optional).")
+	algorithm := flag.String("algorithm", "", "Frequent itemset mining algorithm; fpgrowth, apriori, eclat, declat or bitset. Defaults to fpgrowth, or bitset on dense datasets (optional).")
+	parallelism := flag.Int("parallelism", 0, "Number of goroutines used to read the input and generate rules, defaults to the number of CPUs (optional).")
+	sampleSize := flag.Int("sample", 0, "Number of transactions to sample and mine, verifying with a pass over the dataset (optional).")
+	sampleMinSupport := flag.Float64("sample-min-support", 0, "Support threshold for mining the sample, defaults to 0.8 * min-support (optional).")
+	memoryBudget := flag.Int64("memory-budget", 0, "Megabytes of itemsets to hold in memory before spilling them to disk, 0 for unlimited (optional).")
+	spillDir := flag.String("spill-dir", "", "Directory in which to write spilled itemsets, defaults to the system temporary directory (optional).")
+	checkpoint := flag.String("checkpoint", "", "Directory in which to save the first pass and the progress of itemset generation (optional).")
+	resume := flag.Bool("resume", false, "Resume from the --checkpoint directory, skipping the first pass and already mined items (optional).")
+	reportPath := flag.String("report", "", "File path in which to write a JSON report of the run's metrics (optional).")
+	metricsAddr := flag.String("metrics-addr", "", "Address, such as localhost:9090, on which to serve metrics at /metrics in Prometheus text format while arm runs (optional).")
+	targetItemsets := flag.Int("target-itemsets", 0, "Choose the lowest min-support generating at most this many itemsets, rather than passing --min-support (optional).")
+	targetMemory := flag.Int64("target-memory", 0, "Choose the lowest min-support whose itemsets use at most this many megabytes of memory, rather than passing --min-support (optional).")
+	suggestTimeout := flag.Duration("suggest-timeout", 0, "Time limit for choosing min-support with --target-itemsets or --target-memory, after which the lowest support found so far is used (optional).")
+	noProgress := flag.Bool("no-progress", false, "Don't report the progress of each phase (optional).")
 	enableProfile := flag.Bool("profile", false, "Enables profiling via 'profile' package (optional).")
+	setupLogging := addLogFlags(flag.CommandLine)
 	flag.Parse()
+	logger := setupLogging()
+	logger.Info("Association Rule Mining - in Go via FPGrowth")
+
+	if *resume && len(*checkpoint) == 0 {
+		fmt.Println("--resume requires --checkpoint $dir")
+		os.Exit(-1)
+	}
 
-	if len(*input) == 0 {
+	if len(*input) == 0 && !*resume {
 		fmt.Println("Missing required parameter '--input $csv_path")
 		flag.PrintDefaults()
 		os.Exit(-1)
@@ -69,6 +267,17 @@ func main() {
 		os.Exit(-1)
 	}
 
+	if *targetItemsets < 0 || *targetMemory < 0 || *suggestTimeout < 0 {
+		fmt.Println("Expected --target-itemsets, --target-memory and --suggest-timeout to be non-negative.")
+		os.Exit(-1)
+	}
+
+	suggest := *targetItemsets > 0 || *targetMemory > 0
+	if suggest && *minSupport > 0 {
+		fmt.Println("--min-support can't be combined with --target-itemsets or --target-memory.")
+		os.Exit(-1)
+	}
+
 	if *minConfidence < 0.0 || *minConfidence > 1.0 {
 		fmt.Println("Expected --min-confidence argument followed by float in range [0,1.0].")
 		os.Exit(-1)
@@ -79,49 +288,358 @@ func main() {
 		os.Exit(-1)
 	}
 
+	if *minNegativeConfidence < 0.0 || *minNegativeConfidence > 1.0 {
+		fmt.Println("Expected --min-negative-confidence argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minNegativeLift < 1.0 {
+		fmt.Println("Expected --min-negative-lift argument followed by float in range [1.0,∞].")
+		os.Exit(-1)
+	}
+
+	if *parallelism < 0 {
+		fmt.Println("Expected --parallelism argument to be non-negative.")
+		os.Exit(-1)
+	}
+
+	if *sampleSize < 0 || *sampleMinSupport < 0.0 || *sampleMinSupport > 1.0 {
+		fmt.Println("Expected --sample to be non-negative and --sample-min-support in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *memoryBudget < 0 {
+		fmt.Println("Expected --memory-budget argument to be non-negative.")
+		os.Exit(-1)
+	}
+
+	if *memoryBudget > 0 && (*sampleSize > 0 || len(*algorithm) > 0 || len(*negativeOutput) > 0) {
+		fmt.Println("--memory-budget can't be combined with --sample, --algorithm or --negative-output.")
+		os.Exit(-1)
+	}
+
+	if len(*checkpoint) > 0 && (*sampleSize > 0 || (len(*algorithm) > 0 && *algorithm != "fpgrowth")) {
+		fmt.Println("--checkpoint can only be used with the fpgrowth algorithm, and not with --sample.")
+		os.Exit(-1)
+	}
+
+	var miner fpgrowth.Miner
+	if len(*algorithm) > 0 {
+		miner, err = fpgrowth.MinerByName(*algorithm)
+		if err != nil {
+			fmt.Println("Expected --algorithm argument to be one of fpgrowth, apriori, eclat, declat or bitset.")
+			os.Exit(-1)
+		}
+	}
+
 	if *enableProfile {
 		defer profile.Start().Stop()
 	}
 
-	log.Println("First pass, counting Item frequencies...")
+	// Interrupting arm stops the current phase, and reports how far it got.
+	interrupt, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var run *runReport
+	if len(*reportPath) > 0 || len(*metricsAddr) > 0 {
+		run = newRunReport()
+		defer func() {
+			run.close()
+			run.setStatus(runStatus(err))
+			if len(*reportPath) > 0 {
+				if writeErr := run.write(*reportPath); writeErr != nil {
+					err = errors.Join(err, writeErr)
+					return
+				}
+				logger.Info("wrote run report", "path", *reportPath, "status", runStatus(err))
+			}
+		}()
+	}
+	if len(*metricsAddr) > 0 {
+		stopServing, err := serveMetrics(*metricsAddr, run)
+		if err != nil {
+			return err
+		}
+		defer stopServing()
+	}
+
 	start := time.Now()
-	ctx, err := fpgrowth.Init(*input)
-	check(err)
-	log.Printf("First pass finished in %s", time.Since(start))
+	opts := []fpgrowth.Option{
+		fpgrowth.WithParallelism(*parallelism),
+		fpgrowth.WithLogger(logger),
+	}
+	var reporters multiReporter
+	if !*noProgress {
+		reporters = append(reporters, newProgressReporter(os.Stderr))
+	}
+	if run != nil {
+		reporters = append(reporters, run)
+	}
+	if len(reporters) > 0 {
+		opts = append(opts, fpgrowth.WithProgress(reporters))
+	}
+	if *weighted {
+		opts = append(opts, fpgrowth.WithWeightedTransactions())
+	}
+	if *sampleSize > 0 {
+		opts = append(opts, fpgrowth.WithSample(*sampleSize, time.Now().UnixNano()))
+	}
+	if *memoryBudget > 0 {
+		opts = append(opts, fpgrowth.WithMemoryBudget(*memoryBudget<<20, *spillDir))
+	}
+	var ctx fpgrowth.Context
+	if *resume {
+		logger.Info("resuming from checkpoint", "dir", *checkpoint)
+		ctx, err = fpgrowth.Resume(*checkpoint, opts...)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(*checkpoint) > 0 {
+			opts = append(opts, fpgrowth.WithCheckpoint(*checkpoint))
+		}
+		logger.Info("first pass, counting item frequencies", "input", *input)
+		ctx, err = fpgrowth.InitContext(interrupt, *input, opts...)
+		if err := logInterrupted(err, fpgrowth.PhaseCounting, start); err != nil {
+			return err
+		}
+		logger.Info(
+			"first pass finished",
+			"phase", fpgrowth.PhaseCounting,
+			"duration", time.Since(start),
+		)
+	}
+	run.setDataset(ctx)
 
-	log.Println("Generating frequent itemsets via fpGrowth")
-	start = time.Now()
-	itemsets, err := ctx.GenerateItemsets(*minSupport)
-	check(err)
-	log.Printf("fpGrowth generated %d frequent patterns in %s",
-		len(itemsets), time.Since(start))
+	if suggest {
+		*minSupport, err = suggestMinSupport(interrupt, logger, run, ctx, fpgrowth.ItemsetBudget{
+			Itemsets: *targetItemsets,
+			Bytes:    *targetMemory << 20,
+		}, *suggestTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *memoryBudget > 0 {
+		return generateWithinBudget(interrupt, logger, run, ctx, *minSupport, *minConfidence, *minLift, *itemsetsPath, *output)
+	}
+
+	var itemsets fpgrowth.GeneratedItemsets
+	if *sampleSize > 0 {
+		if *sampleMinSupport == 0 {
+			*sampleMinSupport = 0.8 * *minSupport
+		}
+		logger.Info(
+			"generating frequent itemsets from a sample",
+			"sample", *sampleSize,
+			"min_support", *minSupport,
+			"sample_min_support", *sampleMinSupport,
+		)
+		start = time.Now()
+		result, err := ctx.GenerateItemsetsFromSampleContext(interrupt, *minSupport, *sampleMinSupport, 10)
+		if err := logInterrupted(err, fpgrowth.PhaseMining, start); err != nil {
+			return err
+		}
+		itemsets = result.Itemsets
+		logger.Info(
+			"generated frequent itemsets",
+			"phase", fpgrowth.PhaseMining,
+			"duration", time.Since(start),
+			"itemsets", len(itemsets),
+			"passes", result.Passes,
+		)
+		if !result.Complete {
+			logger.Warn("result isn't guaranteed complete; retry with a lower --sample-min-support or larger --sample")
+		}
+		run.addPhase("sampling", time.Since(start))
+	} else {
+		start = time.Now()
+		logger.Info(
+			"generating frequent itemsets",
+			"algorithm", *algorithm,
+			"min_support", *minSupport,
+		)
+		if miner != nil {
+			itemsets, err = miner.MineContext(interrupt, ctx, *minSupport)
+		} else {
+			itemsets, err = ctx.GenerateItemsetsContext(interrupt, *minSupport)
+		}
+		if err := logInterrupted(err, fpgrowth.PhaseMining, start); err != nil {
+			return err
+		}
+		logger.Info(
+			"generated frequent itemsets",
+			"phase", fpgrowth.PhaseMining,
+			"duration", time.Since(start),
+			"itemsets", len(itemsets),
+		)
+	}
+	run.setItemsets(itemsetLengths(itemsets))
 
 	if len(*itemsetsPath) > 0 {
-		log.Printf("Writing itemsets to '%s'\n", *itemsetsPath)
 		start := time.Now()
-		ctx.WriteItemsets(itemsets, *itemsetsPath)
-		log.Printf(
-			"Wrote %d itemsets in %s",
-			len(itemsets),
-			time.Since(start),
+		err := ctx.WriteItemsetsContext(interrupt, itemsets, *itemsetsPath)
+		if err := logInterrupted(err, "writing itemsets", start); err != nil {
+			return err
+		}
+		run.addPhase("writing itemsets", time.Since(start))
+		logger.Info(
+			"wrote itemsets",
+			"path", *itemsetsPath,
+			"duration", time.Since(start),
+			"itemsets", len(itemsets),
 		)
 	}
 
-	log.Println("Generating association rules...")
+	logger.Info(
+		"generating association rules",
+		"min_confidence", *minConfidence,
+		"min_lift", *minLift,
+	)
 	start = time.Now()
-	rules := ctx.GenerateRules(
+	rules, err := ctx.GenerateRulesContext(
+		interrupt,
 		itemsets,
 		*minConfidence,
 		*minLift,
 	)
-	log.Printf(
-		"Generated %d association rules in %s",
-		len(rules),
-		time.Since(start),
+	if err := logInterrupted(err, fpgrowth.PhaseRules, start, "itemsets", len(itemsets)); err != nil {
+		return err
+	}
+	run.setRules(len(rules))
+	logger.Info(
+		"generated association rules",
+		"phase", fpgrowth.PhaseRules,
+		"duration", time.Since(start),
+		"rules", len(rules),
+	)
+
+	start = time.Now()
+	err = ctx.WriteRulesContext(interrupt, *output, rules)
+	if err := logInterrupted(err, "writing rules", start); err != nil {
+		return err
+	}
+	run.addPhase("writing rules", time.Since(start))
+	logger.Info(
+		"wrote rules",
+		"path", *output,
+		"duration", time.Since(start),
+		"rules", len(rules),
+	)
+
+	if len(*negativeOutput) > 0 {
+		logger.Info(
+			"generating negative association rules",
+			"min_confidence", *minNegativeConfidence,
+			"min_lift", *minNegativeLift,
+		)
+		start = time.Now()
+		negativeRules, err := ctx.GenerateNegativeRulesContext(
+			interrupt,
+			itemsets,
+			*minNegativeConfidence,
+			*minNegativeLift,
+		)
+		if err := logInterrupted(err, "generating negative rules", start); err != nil {
+			return err
+		}
+		logger.Info(
+			"generated negative association rules",
+			"duration", time.Since(start),
+			"rules", len(negativeRules),
+		)
+
+		start = time.Now()
+		if err := ctx.WriteNegativeRules(*negativeOutput, negativeRules); err != nil {
+			return err
+		}
+		run.addPhase("writing negative rules", time.Since(start))
+		logger.Info(
+			"wrote negative rules",
+			"path", *negativeOutput,
+			"duration", time.Since(start),
+			"rules", len(negativeRules),
+		)
+	}
+	return nil
+}
+
+// generateWithinBudget generates itemsets and rules as main does, but spills
+// itemsets beyond the context's memory budget to disk, and writes rules as
+// they're generated rather than holding them in memory.
+func generateWithinBudget(
+	interrupt context.Context,
+	logger *slog.Logger,
+	run *runReport,
+	ctx fpgrowth.Context,
+	minSupport float64,
+	minConfidence float64,
+	minLift float64,
+	itemsetsPath string,
+	output string,
+) error {
+	logger.Info(
+		"generating frequent itemsets within memory budget",
+		"min_support", minSupport,
+	)
+	start := time.Now()
+	itemsets, err := ctx.GenerateSpilledItemsets(interrupt, minSupport)
+	if err := logInterrupted(err, fpgrowth.PhaseMining, start); err != nil {
+		return err
+	}
+	defer itemsets.Close()
+	logger.Info(
+		"generated frequent itemsets",
+		"phase", fpgrowth.PhaseMining,
+		"duration", time.Since(start),
+		"itemsets", itemsets.Len(),
+		"spilled", itemsets.Spilled(),
 	)
+	if run != nil {
+		lengths := make(map[int]int)
+		err := itemsets.ForEach(func(iwc fpgrowth.ItemsetWithCount) error {
+			lengths[len(iwc.Itemset)]++
+			return interrupt.Err()
+		})
+		if err := logInterrupted(err, fpgrowth.PhaseMining, start); err != nil {
+			return err
+		}
+		run.setItemsets(lengths)
+	}
 
+	if len(itemsetsPath) > 0 {
+		start = time.Now()
+		err := ctx.WriteSpilledItemsets(interrupt, itemsets, itemsetsPath)
+		if err := logInterrupted(err, "writing itemsets", start); err != nil {
+			return err
+		}
+		run.addPhase("writing itemsets", time.Since(start))
+		logger.Info(
+			"wrote itemsets",
+			"path", itemsetsPath,
+			"duration", time.Since(start),
+			"itemsets", itemsets.Len(),
+		)
+	}
+
+	logger.Info(
+		"generating association rules",
+		"min_confidence", minConfidence,
+		"min_lift", minLift,
+	)
 	start = time.Now()
-	log.Printf("Writing rules to '%s'...", *output)
-	ctx.WriteRules(*output, rules)
-	log.Printf("Wrote %d rules in %s", len(rules), time.Since(start))
+	numRules, err := ctx.WriteSpilledRules(interrupt, itemsets, minConfidence, minLift, output)
+	if err := logInterrupted(err, fpgrowth.PhaseRules, start, "itemsets", itemsets.Len()); err != nil {
+		return err
+	}
+	run.setRules(numRules)
+	logger.Info(
+		"generated and wrote association rules",
+		"phase", fpgrowth.PhaseRules,
+		"path", output,
+		"duration", time.Since(start),
+		"rules", numRules,
+	)
+	return nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// classify implements the `arm classify` subcommand, which builds a CBA rule
+// classifier from class association rules, and evaluates it on a holdout
+// dataset.
+func classify(args []string) {
+	flags := flag.NewFlagSet("classify", flag.ExitOnError)
+	train := flags.String("train", "", "Training dataset in CSV format; each transaction contains one class item.")
+	holdout := flags.String("holdout", "", "Holdout dataset in CSV format, to evaluate the classifier on (optional).")
+	classList := flags.String("classes", "", "Comma separated list of class items, e.g. 'churned,retained'.")
+	output := flags.String("output", "", "File path in which to store the classifier's rules, in order of application (optional).")
+	minSupport := flags.Float64("min-support", 0, "Minimum itemset support threshold, in range [0,1].")
+	minConfidence := flags.Float64("min-confidence", 0, "Minimum rule confidence threshold, in range [0,1].")
+	scaleSupport := flags.Bool("scale-support", true, "Scale --min-support by each class's support, so rare classes get rules (optional).")
+	weighted := flags.Bool("weighted", false, "First column of each input line is the transaction's count (optional).")
+	setupLogging := addLogFlags(flags)
+	flags.Parse(args)
+	logger := setupLogging()
+
+	if len(*train) == 0 || len(*classList) == 0 {
+		fmt.Println("Missing required parameter '--train $csv_path' or '--classes $class_list'")
+		flags.PrintDefaults()
+		os.Exit(-1)
+	}
+
+	if *minSupport < 0.0 || *minSupport > 1.0 {
+		fmt.Println("Expected --min-support argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minConfidence < 0.0 || *minConfidence > 1.0 {
+		fmt.Println("Expected --min-confidence argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	var opts []fpgrowth.Option
+	if *weighted {
+		opts = append(opts, fpgrowth.WithWeightedTransactions())
+	}
+	classes := strings.Split(*classList, ",")
+
+	logger.Info("first pass, counting item frequencies", "input", *train)
+	start := time.Now()
+	opts = append(opts, fpgrowth.WithLogger(logger))
+	ctx, err := fpgrowth.Init(*train, opts...)
+	check(err)
+	logger.Info("first pass finished", "duration", time.Since(start))
+
+	start = time.Now()
+	var rules []fpgrowth.Rule
+	itemsetsSupport := *minSupport
+	if *scaleSupport {
+		minSupports := ctx.ClassMinSupports(classes, *minSupport)
+		for class, support := range minSupports {
+			itemsetsSupport = min(itemsetsSupport, support)
+			logger.Debug("class minimum support", "class", class, "min_support", support)
+		}
+		itemsets, err := ctx.GenerateItemsets(itemsetsSupport)
+		check(err)
+		rules = ctx.GenerateClassRulesWithMinSupports(itemsets, minSupports, *minConfidence, 0)
+	} else {
+		itemsets, err := ctx.GenerateItemsets(*minSupport)
+		check(err)
+		rules = ctx.GenerateClassRules(itemsets, classes, *minConfidence, 0)
+	}
+	logger.Info(
+		"generated class association rules",
+		"duration", time.Since(start),
+		"min_support", *minSupport,
+		"itemsets_min_support", itemsetsSupport,
+		"min_confidence", *minConfidence,
+		"rules", len(rules),
+	)
+
+	start = time.Now()
+	classifier, err := ctx.BuildClassifier(rules, classes)
+	check(err)
+	logger.Info(
+		"selected rules by database coverage",
+		"duration", time.Since(start),
+		"rules", len(classifier.Rules()),
+	)
+
+	if len(*output) > 0 {
+		check(ctx.WriteRules(*output, classifier.Rules()))
+		logger.Info("wrote classifier rules", "path", *output, "rules", len(classifier.Rules()))
+	}
+
+	if len(*holdout) > 0 {
+		eval, err := classifier.Evaluate(*holdout, opts...)
+		check(err)
+		fmt.Printf("Accuracy: %f\n", eval.Accuracy)
+		fmt.Println("Confusion matrix (rows actual, columns predicted):")
+		fmt.Printf("\t%s\n", strings.Join(eval.Classes, "\t"))
+		for i, row := range eval.Confusion {
+			fmt.Print(eval.Classes[i])
+			for _, n := range row {
+				fmt.Printf("\t%d", n)
+			}
+			fmt.Println()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// diff implements the `arm diff` subcommand, which compares the itemsets and
+// rules of two datasets.
+func diff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	baseline := flags.String("baseline", "", "Baseline dataset in CSV format.")
+	comparison := flags.String("comparison", "", "Comparison dataset in CSV format.")
+	output := flags.String("output", "", "File path in which to store emerging patterns. Format: itemset, supports, growth rate, difference.")
+	rulesPath := flags.String("rules", "", "File path in which to store rule changes (optional).")
+	minSupport := flags.Float64("min-support", 0, "Minimum itemset support threshold in either dataset, in range [0,1].")
+	minGrowthRate := flags.Float64("min-growth-rate", 0, "Minimum ratio of supports between datasets, in either direction (optional).")
+	minDifference := flags.Float64("min-difference", 0, "Minimum absolute difference of supports between datasets (optional).")
+	minConfidence := flags.Float64("min-confidence", 0, "Minimum rule confidence threshold in either dataset, in range [0,1].")
+	minLift := flags.Float64("min-lift", 1, "Minimum rule lift threshold in either dataset, in range [1,∞] (optional)")
+	weighted := flags.Bool("weighted", false, "First column of each input line is the transaction's count (optional).")
+	setupLogging := addLogFlags(flags)
+	flags.Parse(args)
+	logger := setupLogging()
+
+	if len(*baseline) == 0 || len(*comparison) == 0 || len(*output) == 0 {
+		fmt.Println("Missing required parameter '--baseline $csv_path', '--comparison $csv_path' or '--output $path'")
+		flags.PrintDefaults()
+		os.Exit(-1)
+	}
+
+	if *minSupport < 0.0 || *minSupport > 1.0 {
+		fmt.Println("Expected --min-support argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minGrowthRate < 0 || *minDifference < 0 || *minDifference > 1.0 {
+		fmt.Println("Expected --min-growth-rate to be non-negative and --min-difference in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minConfidence < 0.0 || *minConfidence > 1.0 {
+		fmt.Println("Expected --min-confidence argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minLift < 1.0 {
+		fmt.Println("Expected --min-lift argument followed by float in range [1.0,∞].")
+		os.Exit(-1)
+	}
+
+	var opts []fpgrowth.Option
+	if *weighted {
+		opts = append(opts, fpgrowth.WithWeightedTransactions())
+	}
+
+	logger.Info(
+		"first pass, counting item frequencies in both datasets",
+		"baseline", *baseline,
+		"comparison", *comparison,
+	)
+	start := time.Now()
+	ctx, err := fpgrowth.InitContrast(*baseline, *comparison, opts...)
+	check(err)
+	logger.Info("first pass finished", "duration", time.Since(start))
+
+	logger.Info("generating frequent itemsets of both datasets", "min_support", *minSupport)
+	start = time.Now()
+	itemsets, err := ctx.GenerateContrastItemsets(*minSupport)
+	check(err)
+	logger.Info(
+		"generated contrast itemsets",
+		"duration", time.Since(start),
+		"itemsets", len(itemsets.Itemsets),
+	)
+
+	patterns := itemsets.EmergingPatterns(*minGrowthRate, *minDifference)
+	check(ctx.WriteEmergingPatterns(patterns, *output))
+	logger.Info("wrote emerging patterns", "path", *output, "patterns", len(patterns))
+
+	if len(*rulesPath) > 0 {
+		logger.Info(
+			"generating rule changes",
+			"min_confidence", *minConfidence,
+			"min_lift", *minLift,
+		)
+		start = time.Now()
+		changes := ctx.GenerateRuleChanges(itemsets, *minConfidence, *minLift)
+		logger.Info(
+			"generated rule changes",
+			"duration", time.Since(start),
+			"changes", len(changes),
+		)
+		check(ctx.WriteRuleChanges(*rulesPath, changes))
+		logger.Info("wrote rule changes", "path", *rulesPath)
+	}
+}
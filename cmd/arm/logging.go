@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// addLogFlags adds the --log-level and --log-format flags to flags. The
+// returned function, called once flags are parsed, makes slog's default
+// logger write records of that level and format to stderr, and returns it.
+func addLogFlags(flags *flag.FlagSet) func() *slog.Logger {
+	level := flags.String("log-level", "info", "Minimum level of log records; debug, info, warn or error (optional).")
+	format := flags.String("log-format", "text", "Format of log records; text or json (optional).")
+	return func() *slog.Logger {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(*level)); err != nil {
+			fmt.Println("Expected --log-level to be one of debug, info, warn or error.")
+			os.Exit(-1)
+		}
+		opts := &slog.HandlerOptions{Level: l}
+		var handler slog.Handler
+		switch *format {
+		case "text":
+			handler = slog.NewTextHandler(os.Stderr, opts)
+		case "json":
+			handler = slog.NewJSONHandler(os.Stderr, opts)
+		default:
+			fmt.Println("Expected --log-format to be text or json.")
+			os.Exit(-1)
+		}
+		logger := slog.New(handler)
+		slog.SetDefault(logger)
+		return logger
+	}
+}
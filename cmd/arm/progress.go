@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// newProgressReporter returns a reporter which draws a progress bar on w if
+// it's a terminal, and otherwise logs progress periodically.
+func newProgressReporter(w *os.File) fpgrowth.ProgressReporter {
+	if info, err := w.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		return &progressBar{w: w}
+	}
+	return &progressLog{interval: 20 * time.Second, last: time.Now()}
+}
+
+// multiReporter reports progress to each of its reporters.
+type multiReporter []fpgrowth.ProgressReporter
+
+func (m multiReporter) Report(p fpgrowth.Progress) {
+	for _, r := range m {
+		r.Report(p)
+	}
+}
+
+// progressBar draws the progress of each phase on a single line, which is
+// redrawn as it progresses.
+type progressBar struct {
+	w io.Writer
+}
+
+func (b *progressBar) Report(p fpgrowth.Progress) {
+	const width = 30
+	line := fmt.Sprintf("%-16s", p.Phase)
+	if p.Total > 0 {
+		filled := min(width, int(p.Fraction()*width))
+		line += fmt.Sprintf(
+			" [%s%s] %3d%%",
+			strings.Repeat("=", filled),
+			strings.Repeat(" ", width-filled),
+			int(p.Fraction()*100),
+		)
+	}
+	line += describeCounts(p)
+	line += fmt.Sprintf(" %s", p.Elapsed.Round(time.Second))
+	// Return to the start of the line, and clear the rest of it.
+	fmt.Fprintf(b.w, "\r%s\033[K", line)
+	if p.Done {
+		fmt.Fprintln(b.w)
+	}
+}
+
+// progressLog logs the progress of each phase every interval.
+type progressLog struct {
+	interval time.Duration
+	last     time.Time
+}
+
+func (l *progressLog) Report(p fpgrowth.Progress) {
+	if p.Done || time.Since(l.last) < l.interval {
+		return
+	}
+	l.last = time.Now()
+	attrs := []any{"phase", p.Phase, "processed", p.Processed}
+	if p.Total > 0 {
+		attrs = append(attrs, "total", p.Total, "percent", int(p.Fraction()*100+0.5))
+	}
+	if p.Itemsets > 0 {
+		attrs = append(attrs, "itemsets", p.Itemsets)
+	}
+	if p.Rules > 0 {
+		attrs = append(attrs, "rules", p.Rules)
+	}
+	slog.Info("progress", attrs...)
+}
+
+// describeCounts describes the itemsets and rules generated so far.
+func describeCounts(p fpgrowth.Progress) string {
+	s := ""
+	if p.Itemsets > 0 {
+		s += fmt.Sprintf(", %d itemsets", p.Itemsets)
+	}
+	if p.Rules > 0 {
+		s += fmt.Sprintf(", %d rules", p.Rules)
+	}
+	return s
+}
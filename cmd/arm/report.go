@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// heapSampleInterval is how often the heap size is sampled to find its peak.
+const heapSampleInterval = 250 * time.Millisecond
+
+// phaseMetrics stores the metrics of a phase of a run. A phase may run more
+// than once, for example counting items in several files, in which case its
+// durations are summed.
+type phaseMetrics struct {
+	Phase     string  `json:"phase"`
+	Seconds   float64 `json:"duration_seconds"`
+	Processed int64   `json:"processed,omitempty"`
+	Total     int64   `json:"total,omitempty"`
+	// finished is the duration of the phase's previous runs, and running the
+	// duration of its current or last run.
+	finished time.Duration
+	running  time.Duration
+	done     bool
+}
+
+// runReport collects metrics of a run of arm, which are written as JSON with
+// --report, and served in Prometheus text format with --metrics-addr. It's a
+// ProgressReporter, so the fpgrowth package reports each phase's progress and
+// duration to it. A nil *runReport is valid, and ignores updates.
+type runReport struct {
+	mu               sync.Mutex
+	start            time.Time
+	phases           []*phaseMetrics
+	heap             uint64
+	peakHeap         uint64
+	transactions     int
+	items            int
+	treeNodes        int64
+	itemsets         int64
+	itemsetsByLength map[int]int
+	rules            int64
+	status           string
+	stop             chan struct{}
+	stopped          chan struct{}
+}
+
+// newRunReport starts collecting metrics, sampling the heap size until
+// close is called.
+func newRunReport() *runReport {
+	r := &runReport{
+		start:            time.Now(),
+		itemsetsByLength: make(map[int]int),
+		stop:             make(chan struct{}),
+		stopped:          make(chan struct{}),
+	}
+	r.sampleHeap()
+	go func() {
+		defer close(r.stopped)
+		ticker := time.NewTicker(heapSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.sampleHeap()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+	return r
+}
+
+// close stops sampling the heap size.
+func (r *runReport) close() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.stopped
+	r.sampleHeap()
+}
+
+func (r *runReport) sampleHeap() {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return
+	}
+	heap := sample[0].Value.Uint64()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.heap = heap
+	r.peakHeap = max(r.peakHeap, heap)
+}
+
+// phase returns the metrics of the phase called name, adding them if needed.
+// r.mu must be held.
+func (r *runReport) phase(name string) *phaseMetrics {
+	for _, p := range r.phases {
+		if p.Phase == name {
+			return p
+		}
+	}
+	p := &phaseMetrics{Phase: name}
+	r.phases = append(r.phases, p)
+	return p
+}
+
+func (r *runReport) Report(p fpgrowth.Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	phase := r.phase(string(p.Phase))
+	if phase.done {
+		// The phase is running again.
+		phase.finished += phase.running
+		phase.done = false
+	}
+	phase.running = p.Elapsed
+	phase.done = p.Done
+	phase.Processed = p.Processed
+	phase.Total = p.Total
+	switch p.Phase {
+	case fpgrowth.PhaseBuildingTree:
+		r.treeNodes = max(r.treeNodes, p.TreeNodes)
+	case fpgrowth.PhaseMining:
+		r.itemsets = p.Itemsets
+	case fpgrowth.PhaseRules:
+		r.rules = p.Rules
+	}
+}
+
+// addPhase records that the phase called name, which the fpgrowth package
+// doesn't report, took duration.
+func (r *runReport) addPhase(name string, duration time.Duration) {
+	if r != nil {
+		r.Report(fpgrowth.Progress{Phase: fpgrowth.Phase(name), Elapsed: duration, Done: true})
+	}
+}
+
+func (r *runReport) setDataset(ctx fpgrowth.Context) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transactions = ctx.NumTransactions()
+	r.items = ctx.NumItems()
+}
+
+// setItemsets records the number of itemsets generated of each length.
+func (r *runReport) setItemsets(lengths map[int]int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.itemsetsByLength = lengths
+	r.itemsets = 0
+	for _, count := range lengths {
+		r.itemsets += int64(count)
+	}
+}
+
+// itemsetLengths returns the number of itemsets of each length.
+func itemsetLengths(itemsets fpgrowth.GeneratedItemsets) map[int]int {
+	lengths := make(map[int]int)
+	for _, iwc := range itemsets {
+		lengths[len(iwc.Itemset)]++
+	}
+	return lengths
+}
+
+func (r *runReport) setRules(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = int64(n)
+}
+
+// setStatus records how the run ended; completed, interrupted or failed.
+func (r *runReport) setStatus(status string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// reportJSON is the JSON form of a runReport.
+type reportJSON struct {
+	Status           string          `json:"status"`
+	DurationSeconds  float64         `json:"duration_seconds"`
+	Phases           []*phaseMetrics `json:"phases"`
+	PeakHeapBytes    uint64          `json:"peak_heap_bytes"`
+	Transactions     int             `json:"transactions"`
+	Items            int             `json:"items"`
+	TreeNodes        int64           `json:"tree_nodes"`
+	Itemsets         int64           `json:"itemsets"`
+	ItemsetsByLength map[int]int     `json:"itemsets_by_length"`
+	Rules            int64           `json:"rules"`
+}
+
+// write writes the report as JSON to the file at path.
+func (r *runReport) write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.phases {
+		p.Seconds = (p.finished + p.running).Seconds()
+	}
+	report := reportJSON{
+		Status:           r.status,
+		DurationSeconds:  time.Since(r.start).Seconds(),
+		Phases:           r.phases,
+		PeakHeapBytes:    r.peakHeap,
+		Transactions:     r.transactions,
+		Items:            r.items,
+		TreeNodes:        r.treeNodes,
+		Itemsets:         r.itemsets,
+		ItemsetsByLength: r.itemsetsByLength,
+		Rules:            r.rules,
+	}
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(output, '\n'), 0644)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric writes a gauge in Prometheus text format, with a value for each
+// label value in values, or a single unlabelled value if label is empty.
+func writeMetric(w io.Writer, name, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if label == "" {
+			fmt.Fprintf(w, "%s %g\n", name, values[key])
+		} else {
+			fmt.Fprintf(w, "%s{%s=\"%s\"} %g\n", name, label, labelEscaper.Replace(key), values[key])
+		}
+	}
+}
+
+// ServeHTTP serves the report's metrics in Prometheus text format.
+func (r *runReport) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	single := func(v float64) map[string]float64 {
+		return map[string]float64{"": v}
+	}
+	durations := make(map[string]float64)
+	processed := make(map[string]float64)
+	totals := make(map[string]float64)
+	for _, p := range r.phases {
+		durations[p.Phase] = (p.finished + p.running).Seconds()
+		processed[p.Phase] = float64(p.Processed)
+		totals[p.Phase] = float64(p.Total)
+	}
+	lengths := make(map[string]float64)
+	for n, count := range r.itemsetsByLength {
+		lengths[fmt.Sprint(n)] = float64(count)
+	}
+	writeMetric(w, "arm_run_duration_seconds", "Time since arm started.", "", single(time.Since(r.start).Seconds()))
+	writeMetric(w, "arm_phase_duration_seconds", "Time spent in each phase.", "phase", durations)
+	writeMetric(w, "arm_phase_processed", "Work done in each phase, out of arm_phase_total.", "phase", processed)
+	writeMetric(w, "arm_phase_total", "Total work of each phase, or zero if unknown.", "phase", totals)
+	writeMetric(w, "arm_heap_bytes", "Bytes of heap objects.", "", single(float64(r.heap)))
+	writeMetric(w, "arm_heap_peak_bytes", "Peak bytes of heap objects.", "", single(float64(r.peakHeap)))
+	writeMetric(w, "arm_transactions", "Number of transactions in the input.", "", single(float64(r.transactions)))
+	writeMetric(w, "arm_items", "Number of distinct items in the input.", "", single(float64(r.items)))
+	writeMetric(w, "arm_tree_nodes", "Number of nodes in the FP-tree.", "", single(float64(r.treeNodes)))
+	writeMetric(w, "arm_itemsets", "Number of frequent itemsets generated.", "", single(float64(r.itemsets)))
+	writeMetric(w, "arm_itemsets_by_length", "Number of frequent itemsets of each length.", "length", lengths)
+	writeMetric(w, "arm_rules", "Number of association rules generated.", "", single(float64(r.rules)))
+}
+
+// serveMetrics serves r's metrics at /metrics on addr until the returned
+// function is called.
+func serveMetrics(addr string, r *runReport) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	server := &http.Server{Handler: mux}
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("serving metrics failed", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("serving metrics", "url", fmt.Sprintf("http://%s/metrics", listener.Addr()))
+	return func() {
+		server.Shutdown(context.Background())
+	}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// sequences implements the `arm sequences` subcommand, which generates
+// sequential patterns and sequential rules.
+func sequences(args []string) {
+	flags := flag.NewFlagSet("sequences", flag.ExitOnError)
+	input := flags.String("input", "", "Input sequences, one per line, with itemsets separated by ';' and items by ','.")
+	output := flags.String("output", "", "File path in which to store output sequential rules. Format: antecedent => consequent, confidence, lift, support.")
+	patternsPath := flags.String("patterns", "", "File path in which to store generated sequential patterns (optional).")
+	minSupport := flags.Float64("min-support", 0, "Minimum sequence support threshold, in range [0,1].")
+	minConfidence := flags.Float64("min-confidence", 0, "Minimum rule confidence threshold, in range [0,1].")
+	minLift := flags.Float64("min-lift", 1, "Minimum rule lift confidence threshold, in range [1,∞] (optional)")
+	maxGap := flags.Int("max-gap", 0, "Maximum distance between consecutive itemsets of a pattern, 0 for unconstrained (optional).")
+	maxWindow := flags.Int("max-window", 0, "Maximum distance between first and last itemsets of a pattern, 0 for unconstrained (optional).")
+	setupLogging := addLogFlags(flags)
+	flags.Parse(args)
+	logger := setupLogging()
+
+	if len(*input) == 0 || len(*output) == 0 {
+		fmt.Println("Missing required parameter '--input $csv_path' or '--output $rule_path'")
+		flags.PrintDefaults()
+		os.Exit(-1)
+	}
+
+	if *minSupport < 0.0 || *minSupport > 1.0 {
+		fmt.Println("Expected --min-support argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minConfidence < 0.0 || *minConfidence > 1.0 {
+		fmt.Println("Expected --min-confidence argument followed by float in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	if *minLift < 1.0 {
+		fmt.Println("Expected --min-lift argument followed by float in range [1.0,∞].")
+		os.Exit(-1)
+	}
+
+	if *maxGap < 0 || *maxWindow < 0 {
+		fmt.Println("Expected --max-gap and --max-window to be non-negative.")
+		os.Exit(-1)
+	}
+
+	logger.Info("first pass, counting item frequencies", "input", *input)
+	start := time.Now()
+	ctx, err := fpgrowth.InitSequences(*input)
+	check(err)
+	logger.Info("first pass finished", "duration", time.Since(start))
+
+	logger.Info(
+		"generating sequential patterns via PrefixSpan",
+		"min_support", *minSupport,
+		"max_gap", *maxGap,
+		"max_window", *maxWindow,
+	)
+	start = time.Now()
+	patterns, err := ctx.GenerateSequentialPatterns(
+		*minSupport,
+		fpgrowth.SequenceConstraints{MaxGap: *maxGap, MaxWindow: *maxWindow},
+	)
+	check(err)
+	logger.Info(
+		"generated sequential patterns",
+		"duration", time.Since(start),
+		"patterns", len(patterns),
+	)
+
+	if len(*patternsPath) > 0 {
+		check(ctx.WriteSequentialPatterns(patterns, *patternsPath))
+		logger.Info("wrote sequential patterns", "path", *patternsPath, "patterns", len(patterns))
+	}
+
+	logger.Info(
+		"generating sequential rules",
+		"min_confidence", *minConfidence,
+		"min_lift", *minLift,
+	)
+	start = time.Now()
+	rules := ctx.GenerateSequentialRules(patterns, *minConfidence, *minLift)
+	logger.Info(
+		"generated sequential rules",
+		"duration", time.Since(start),
+		"rules", len(rules),
+	)
+
+	start = time.Now()
+	check(ctx.WriteSequentialRules(*output, rules))
+	logger.Info(
+		"wrote rules",
+		"path", *output,
+		"duration", time.Since(start),
+		"rules", len(rules),
+	)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+const defaultCandidateSupports = "0.5,0.2,0.1,0.05,0.02,0.01,0.005,0.002,0.001"
+
+// lengthBucket counts the transactions with between From and To items.
+type lengthBucket struct {
+	From         int `json:"from"`
+	To           int `json:"to"`
+	Transactions int `json:"transactions"`
+}
+
+// longTail describes the items outside the top N.
+type longTail struct {
+	Items int `json:"items"`
+	// Occurrences is the fraction of all item occurrences which are of
+	// items in the long tail.
+	Occurrences float64 `json:"occurrences"`
+	// Singletons is the number of items which occur in only one transaction.
+	Singletons int `json:"singletons"`
+}
+
+type itemJSON struct {
+	Item    string  `json:"item"`
+	Count   int     `json:"count"`
+	Support float64 `json:"support"`
+}
+
+type survivorsJSON struct {
+	MinSupport float64 `json:"min_support"`
+	Items      int     `json:"items"`
+	Density    float64 `json:"density"`
+}
+
+// statsReport is the output of `arm stats`.
+type statsReport struct {
+	Transactions  int             `json:"transactions"`
+	Items         int             `json:"items"`
+	Density       float64         `json:"density"`
+	AverageLength float64         `json:"average_length"`
+	MedianLength  int             `json:"median_length"`
+	P90Length     int             `json:"p90_length"`
+	MaxLength     int             `json:"max_length"`
+	Lengths       []lengthBucket  `json:"lengths"`
+	TopItems      []itemJSON      `json:"top_items"`
+	LongTail      longTail        `json:"long_tail"`
+	Survivors     []survivorsJSON `json:"survivors"`
+}
+
+// lengthBuckets groups transaction lengths into buckets of 1, 2-3, 4-7, etc.
+func lengthBuckets(lengths []int) []lengthBucket {
+	buckets := make([]lengthBucket, 0)
+	for from := 1; from < len(lengths); from *= 2 {
+		bucket := lengthBucket{From: from, To: 2*from - 1}
+		for length := from; length <= bucket.To && length < len(lengths); length++ {
+			bucket.Transactions += lengths[length]
+		}
+		buckets = append(buckets, bucket)
+	}
+	if len(lengths) > 0 && lengths[0] > 0 {
+		buckets = append([]lengthBucket{{0, 0, lengths[0]}}, buckets...)
+	}
+	return buckets
+}
+
+func makeStatsReport(ctx fpgrowth.Context, top int, supports []float64) statsReport {
+	stats := ctx.Stats()
+	report := statsReport{
+		Transactions:  stats.Transactions,
+		Items:         stats.Items,
+		Density:       stats.Density,
+		AverageLength: stats.AverageLength(),
+		MedianLength:  stats.LengthQuantile(0.5),
+		P90Length:     stats.LengthQuantile(0.9),
+		MaxLength:     stats.LengthQuantile(1),
+		Lengths:       lengthBuckets(stats.Lengths),
+		TopItems:      make([]itemJSON, 0, top),
+	}
+	occurrences, tailOccurrences := 0, 0
+	for i, item := range stats.ItemSupports {
+		occurrences += item.Count
+		if i < top {
+			report.TopItems = append(report.TopItems, itemJSON(item))
+			continue
+		}
+		report.LongTail.Items++
+		tailOccurrences += item.Count
+		if item.Count == 1 {
+			report.LongTail.Singletons++
+		}
+	}
+	if occurrences > 0 {
+		report.LongTail.Occurrences = float64(tailOccurrences) / float64(occurrences)
+	}
+	for _, s := range ctx.Survivors(supports) {
+		report.Survivors = append(report.Survivors, survivorsJSON(s))
+	}
+	return report
+}
+
+func writeStatsText(w io.Writer, report statsReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Transactions:\t%d\n", report.Transactions)
+	fmt.Fprintf(tw, "Distinct items:\t%d\n", report.Items)
+	fmt.Fprintf(tw, "Density:\t%.4f\n", report.Density)
+	fmt.Fprintf(tw, "Transaction length:\tmean %.2f, median %d, 90th percentile %d, max %d\n",
+		report.AverageLength, report.MedianLength, report.P90Length, report.MaxLength)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Length\tTransactions\tFraction")
+	for _, b := range report.Lengths {
+		span := fmt.Sprint(b.From)
+		if b.To > b.From {
+			span = fmt.Sprintf("%d-%d", b.From, b.To)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.2f%%\n", span, b.Transactions,
+			100*float64(b.Transactions)/float64(max(1, report.Transactions)))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Top %d items\tCount\tSupport\n", len(report.TopItems))
+	for _, item := range report.TopItems {
+		fmt.Fprintf(tw, "%s\t%d\t%.4f\n", item.Item, item.Count, item.Support)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "Long tail:\t%d other items, %.2f%% of item occurrences, %d occur once\n",
+		report.LongTail.Items, 100*report.LongTail.Occurrences, report.LongTail.Singletons)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Min support\tFrequent items\tDensity")
+	for _, s := range report.Survivors {
+		fmt.Fprintf(tw, "%g\t%d\t%.4f\n", s.MinSupport, s.Items, s.Density)
+	}
+	return tw.Flush()
+}
+
+// parseSupports parses a comma separated list of supports.
+func parseSupports(list string) ([]float64, error) {
+	supports := make([]float64, 0)
+	for _, field := range strings.Split(list, ",") {
+		support, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || support < 0 || support > 1 {
+			return nil, fmt.Errorf("invalid support %q", field)
+		}
+		supports = append(supports, support)
+	}
+	return supports, nil
+}
+
+// stats implements the `arm stats` subcommand, which describes a dataset to
+// help choose thresholds.
+func stats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	input := flags.String("input", "", "Input dataset in CSV format.")
+	output := flags.String("output", "", "File path in which to write the statistics, defaults to stdout (optional).")
+	format := flags.String("format", "text", "Output format; text or json (optional).")
+	top := flags.Int("top", 20, "Number of most frequent items to list (optional).")
+	supportList := flags.String("supports", defaultCandidateSupports, "Comma separated candidate min-support values, for which the number of frequent items is reported (optional).")
+	weighted := flags.Bool("weighted", false, "First column of each input line is the transaction's count (optional).")
+	setupLogging := addLogFlags(flags)
+	flags.Parse(args)
+	logger := setupLogging()
+
+	if len(*input) == 0 {
+		fmt.Println("Missing required parameter '--input $csv_path'")
+		flags.PrintDefaults()
+		os.Exit(-1)
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Println("Expected --format to be text or json.")
+		os.Exit(-1)
+	}
+
+	if *top < 0 {
+		fmt.Println("Expected --top argument to be non-negative.")
+		os.Exit(-1)
+	}
+
+	supports, err := parseSupports(*supportList)
+	if err != nil {
+		fmt.Println("Expected --supports to be a comma separated list of floats in range [0,1.0].")
+		os.Exit(-1)
+	}
+
+	opts := []fpgrowth.Option{fpgrowth.WithLogger(logger)}
+	if *weighted {
+		opts = append(opts, fpgrowth.WithWeightedTransactions())
+	}
+	logger.Info("first pass, counting item frequencies", "input", *input)
+	start := time.Now()
+	ctx, err := fpgrowth.Init(*input, opts...)
+	check(err)
+	logger.Info("first pass finished", "duration", time.Since(start))
+
+	w := os.Stdout
+	if len(*output) > 0 {
+		w, err = os.Create(*output)
+		check(err)
+		defer w.Close()
+	}
+	report := makeStatsReport(ctx, *top, supports)
+	if *format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		check(encoder.Encode(report))
+	} else {
+		check(writeStatsText(w, report))
+	}
+}
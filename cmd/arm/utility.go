@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cpearce/arm-go/fpgrowth"
+)
+
+// utility implements the `arm utility` subcommand, which generates high
+// utility itemsets.
+func utility(args []string) {
+	flags := flag.NewFlagSet("utility", flag.ExitOnError)
+	input := flags.String("input", "", "Input dataset in CSV format, with items of the form item:quantity.")
+	profits := flags.String("profits", "", "CSV file of item,unit-profit pairs.")
+	output := flags.String("output", "", "File path in which to store high utility itemsets. Format: itemset, utility, count.")
+	minUtility := flags.Float64("min-utility", 0, "Minimum itemset utility threshold.")
+	setupLogging := addLogFlags(flags)
+	flags.Parse(args)
+	logger := setupLogging()
+
+	if len(*input) == 0 || len(*profits) == 0 || len(*output) == 0 {
+		fmt.Println("Missing required parameter '--input $csv_path', '--profits $csv_path' or '--output $itemsets_path'")
+		flags.PrintDefaults()
+		os.Exit(-1)
+	}
+
+	if *minUtility <= 0 {
+		fmt.Println("Expected --min-utility argument followed by a positive float.")
+		os.Exit(-1)
+	}
+
+	logger.Info("first pass, computing transaction weighted utilities", "input", *input)
+	start := time.Now()
+	ctx, err := fpgrowth.InitUtility(*input, *profits)
+	check(err)
+	logger.Info(
+		"first pass finished",
+		"duration", time.Since(start),
+		"total_utility", ctx.TotalUtility(),
+	)
+
+	logger.Info("generating high utility itemsets via FHM", "min_utility", *minUtility)
+	start = time.Now()
+	itemsets, err := ctx.GenerateHighUtilityItemsets(*minUtility)
+	check(err)
+	logger.Info(
+		"generated high utility itemsets",
+		"duration", time.Since(start),
+		"itemsets", len(itemsets),
+	)
+
+	start = time.Now()
+	check(ctx.WriteHighUtilityItemsets(itemsets, *output))
+	logger.Info(
+		"wrote itemsets",
+		"path", *output,
+		"duration", time.Since(start),
+		"itemsets", len(itemsets),
+	)
+}
@@ -0,0 +1,144 @@
+package fpgrowth
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+)
+
+// bitset is a set of transaction indices, stored one bit per transaction.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) set(idx int) {
+	b[idx/64] |= 1 << uint(idx%64)
+}
+
+// intersect stores the intersection of a and b in dst, and returns the number
+// of elements in it.
+func (dst bitset) intersect(a, b bitset) int {
+	count := 0
+	for i := range dst {
+		dst[i] = a[i] & b[i]
+		count += bits.OnesCount64(dst[i])
+	}
+	return count
+}
+
+// Bitset mines itemsets from a vertical representation of the dataset, like
+// Eclat, but stores each itemset's transactions as a bitset rather than a
+// list. Supports are counted by intersecting bitsets word by word and counting
+// the bits set. This is fast and compact on dense datasets, where most items
+// occur in a large proportion of transactions, but wastes memory on sparse
+// ones. Weighted transactions take one bit per occurrence.
+type Bitset struct{}
+
+type bitsetNode struct {
+	item    Item
+	tids    bitset
+	support int
+}
+
+type bitsetMiner struct {
+	c        context.Context
+	progress *progressTracker
+	minCount int
+	size     int
+	itemsets GeneratedItemsets
+}
+
+// Mine generates frequent itemsets with support above minSupport.
+func (b Bitset) Mine(ctx Context, minSupport float64) (GeneratedItemsets, error) {
+	return b.MineContext(context.Background(), ctx, minSupport)
+}
+
+// MineContext generates frequent itemsets with support above minSupport,
+// stopping if c is cancelled.
+func (Bitset) MineContext(
+	c context.Context,
+	ctx Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	minCount := minCountFor(minSupport, ctx.numTransactions)
+	items := frequentItems(&ctx, minCount)
+	index := make(map[Item]int, len(items))
+	for i, item := range items {
+		index[item] = i
+	}
+
+	// Each item's bitset has a bit per transaction, counting weighted
+	// transactions once per occurrence, so the number of bits is known from
+	// the first pass.
+	size := ctx.numTransactions
+	class := make([]bitsetNode, len(items))
+	for i, item := range items {
+		class[i] = bitsetNode{item: item, tids: newBitset(size)}
+	}
+	tid := 0
+	err := ctx.forEachItemset(c, func(transaction []Item, count int) {
+		for c := 0; c < count; c++ {
+			for _, item := range transaction {
+				if i, found := index[item]; found {
+					class[i].tids.set(tid)
+					class[i].support++
+				}
+			}
+			tid++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(class, func(i, j int) bool {
+		return class[i].support < class[j].support
+	})
+
+	progress := startProgress(ctx.options.progress, PhaseMining, int64(len(class)))
+	defer progress.finish()
+	m := &bitsetMiner{
+		c:        c,
+		progress: progress,
+		minCount: minCount,
+		size:     size,
+		itemsets: make(GeneratedItemsets, 0),
+	}
+	m.mine(make([]Item, 0), class)
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return m.itemsets, nil
+}
+
+func (m *bitsetMiner) mine(prefix []Item, class []bitsetNode) {
+	for i, x := range class {
+		if cancelled(m.c) {
+			return
+		}
+		itemset := appendSorted(prefix, x.item)
+		m.itemsets = append(m.itemsets, ItemsetWithCount{itemset, x.support})
+		m.progress.addItemsets(1)
+		next := make([]bitsetNode, 0, len(class)-i-1)
+		var tids bitset
+		for _, y := range class[i+1:] {
+			if tids == nil {
+				tids = newBitset(m.size)
+			}
+			support := tids.intersect(x.tids, y.tids)
+			if support >= m.minCount {
+				next = append(next, bitsetNode{y.item, tids, support})
+				// Keep this bitset, and allocate another for the next
+				// candidate.
+				tids = nil
+			}
+		}
+		if len(next) > 0 {
+			m.mine(itemset, next)
+		}
+		if len(prefix) == 0 {
+			m.progress.add(1)
+		}
+	}
+}
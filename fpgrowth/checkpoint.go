@@ -0,0 +1,307 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Files in a checkpoint directory.
+const (
+	checkpointContextFile  = "context"
+	checkpointItemsetsFile = "itemsets"
+	checkpointProgressFile = "progress"
+)
+
+// checkpointComplete is the last line of a checkpoint's progress file once
+// every item has been mined.
+const checkpointComplete = "complete"
+
+// WithCheckpoint saves the Context's first pass to dir, and while generating
+// itemsets records which frequent items' conditional FP-trees have been mined
+// in dir, along with the itemsets generated from them. If the process is
+// stopped, Resume() restores the Context from dir, and generating itemsets at
+// the same minimum support then skips the items which were already mined,
+// producing the same itemsets in the same order. Checkpointed Contexts always
+// mine with FP-growth. Can't be combined with WithIncrementalMining() or
+// WithSample().
+func WithCheckpoint(dir string) Option {
+	return func(o *options) {
+		o.checkpointDir = dir
+	}
+}
+
+// savedContext is the part of a Context saved to a checkpoint.
+type savedContext struct {
+	InputCsvPaths []string
+	// Items stores the string of each Item, in order starting from Item 1.
+	Items           []string
+	Frequency       []int
+	NumTransactions int
+	Lengths         []int
+	Weighted        bool
+}
+
+// Resume restores a Context saved to dir by Init() with WithCheckpoint(dir),
+// without reading the dataset again. opts are applied as for Init(), except
+// that whether transactions are weighted is restored from the checkpoint.
+func Resume(dir string, opts ...Option) (Context, error) {
+	o := makeOptions(opts)
+	if o.incremental || o.sampleSize > 0 {
+		return Context{}, errors.New("checkpoints can't be used with incremental mining or sampling")
+	}
+	file, err := os.Open(filepath.Join(dir, checkpointContextFile))
+	if err != nil {
+		return Context{}, err
+	}
+	defer file.Close()
+	var saved savedContext
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&saved); err != nil {
+		return Context{}, fmt.Errorf("%s: %w", file.Name(), err)
+	}
+	o.weighted = saved.Weighted
+	o.checkpointDir = dir
+	ctx := newContext(o)
+	ctx.inputCsvPaths = saved.InputCsvPaths
+	for _, s := range saved.Items {
+		ctx.itemizer.item(s)
+	}
+	ctx.frequency.counts = saved.Frequency
+	ctx.numTransactions = saved.NumTransactions
+	ctx.lengths = saved.Lengths
+	return ctx, nil
+}
+
+// saveCheckpoint saves the Context to its checkpoint directory, discarding
+// the progress of any previous mining.
+func (ctx *Context) saveCheckpoint() error {
+	dir := ctx.options.checkpointDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	saved := savedContext{
+		InputCsvPaths:   ctx.inputCsvPaths,
+		Items:           make([]string, ctx.itemizer.numItems),
+		Frequency:       ctx.frequency.counts,
+		NumTransactions: ctx.numTransactions,
+		Lengths:         ctx.lengths,
+		Weighted:        ctx.options.weighted,
+	}
+	for i := range saved.Items {
+		saved.Items[i] = ctx.itemizer.ToStr(Item(i + 1))
+	}
+	// Write to a temporary file and rename it, so a checkpoint is never left
+	// half written.
+	file, err := os.CreateTemp(dir, checkpointContextFile+"-")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	err = gob.NewEncoder(w).Encode(&saved)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(dir, checkpointContextFile))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	for _, name := range []string{checkpointItemsetsFile, checkpointProgressFile} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkpointEntry records that an item's itemsets have been generated, and
+// end, the size of the itemsets file after they were written to it.
+type checkpointEntry struct {
+	item Item
+	end  int64
+}
+
+// miningCheckpoint records the progress of mining at minCount in a
+// checkpoint directory. The itemsets generated from each item are appended to
+// the itemsets file, and then the item is appended to the progress file. So
+// the itemsets of every item in the progress file are in the itemsets file,
+// and anything in the itemsets file beyond them is discarded on resuming.
+type miningCheckpoint struct {
+	itemsets *os.File
+	progress *os.File
+	entries  []checkpointEntry
+	done     map[Item]bool
+	complete bool
+	size     int64
+	buf      []byte
+}
+
+// openMiningCheckpoint opens the record of mining at minCount in dir,
+// starting a new one if there's none, or it's for a different minCount.
+func openMiningCheckpoint(dir string, minCount int) (*miningCheckpoint, error) {
+	header := fmt.Sprintf("minCount %d", minCount)
+	cp := &miningCheckpoint{done: make(map[Item]bool)}
+	var err error
+	cp.progress, err = os.OpenFile(
+		filepath.Join(dir, checkpointProgressFile),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+	if err != nil {
+		return nil, err
+	}
+	cp.itemsets, err = os.OpenFile(
+		filepath.Join(dir, checkpointItemsetsFile),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+	if err != nil {
+		cp.progress.Close()
+		return nil, err
+	}
+	valid, err := cp.readProgress(header)
+	if err == nil && !valid {
+		// Start again.
+		cp.entries = nil
+		cp.done = make(map[Item]bool)
+		cp.complete = false
+		if err = cp.progress.Truncate(0); err == nil {
+			_, err = cp.progress.Seek(0, io.SeekStart)
+		}
+		if err == nil {
+			_, err = fmt.Fprintln(cp.progress, header)
+		}
+	}
+	if err == nil {
+		// Discard any itemsets written after the last item was recorded.
+		if len(cp.entries) > 0 {
+			cp.size = cp.entries[len(cp.entries)-1].end
+		}
+		err = cp.itemsets.Truncate(cp.size)
+	}
+	if err == nil {
+		_, err = cp.itemsets.Seek(cp.size, io.SeekStart)
+	}
+	if err != nil {
+		cp.close()
+		return nil, err
+	}
+	return cp, nil
+}
+
+// readProgress reads the progress file, returning false if it isn't a
+// record of mining with header. A partially written last line is removed.
+func (cp *miningCheckpoint) readProgress(header string) (bool, error) {
+	r := bufio.NewReader(cp.progress)
+	offset := int64(0)
+	for lineNum := 0; ; lineNum++ {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			// Truncate any partial line, and append after the last full one.
+			if err := cp.progress.Truncate(offset); err != nil {
+				return false, err
+			}
+			_, err := cp.progress.Seek(offset, io.SeekStart)
+			return lineNum > 0, err
+		} else if err != nil {
+			return false, err
+		}
+		offset += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		if lineNum == 0 {
+			if line != header {
+				return false, nil
+			}
+			continue
+		}
+		if line == checkpointComplete {
+			cp.complete = true
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return false, nil
+		}
+		item, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return false, nil
+		}
+		end, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return false, nil
+		}
+		cp.entries = append(cp.entries, checkpointEntry{Item(item), end})
+		cp.done[Item(item)] = true
+	}
+}
+
+// replay calls emit with the recorded itemsets of each mined item, in the
+// order they were mined.
+func (cp *miningCheckpoint) replay(emit func(itemsets []ItemsetWithCount) error) error {
+	start := int64(0)
+	itemsets := make([]ItemsetWithCount, 0)
+	for _, entry := range cp.entries {
+		r := bufio.NewReader(io.NewSectionReader(cp.itemsets, start, entry.end-start))
+		itemsets = itemsets[:0]
+		for {
+			iwc, err := readItemsetRecord(r)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", cp.itemsets.Name(), err)
+			}
+			itemsets = append(itemsets, iwc)
+		}
+		start = entry.end
+		if err := emit(itemsets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record durably records that item has been mined, generating itemsets.
+func (cp *miningCheckpoint) record(item Item, itemsets []ItemsetWithCount) error {
+	cp.buf = cp.buf[:0]
+	for _, iwc := range itemsets {
+		cp.buf = appendItemsetRecord(cp.buf, iwc)
+	}
+	if _, err := cp.itemsets.Write(cp.buf); err != nil {
+		return err
+	}
+	if err := cp.itemsets.Sync(); err != nil {
+		return err
+	}
+	cp.size += int64(len(cp.buf))
+	if _, err := fmt.Fprintf(cp.progress, "%d %d\n", item, cp.size); err != nil {
+		return err
+	}
+	return cp.progress.Sync()
+}
+
+// finish records that every item has been mined.
+func (cp *miningCheckpoint) finish() error {
+	if _, err := fmt.Fprintln(cp.progress, checkpointComplete); err != nil {
+		return err
+	}
+	return cp.progress.Sync()
+}
+
+func (cp *miningCheckpoint) close() {
+	cp.itemsets.Close()
+	cp.progress.Close()
+}
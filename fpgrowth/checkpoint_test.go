@@ -0,0 +1,94 @@
+package fpgrowth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	path := writeTestCsv(t, randomTransactions(500, 10)...)
+	const minSupport = 0.02
+	uncheckpointed, err := Init(path, WithDensityThreshold(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := uncheckpointed.GenerateItemsets(minSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	ctx, err := Init(path, WithCheckpoint(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stop mining after the itemsets of three items have been recorded.
+	stop := errors.New("stopped")
+	numItems := 0
+	err = ctx.mineFrequentItemsets(context.Background(), minSupport, func([]ItemsetWithCount) error {
+		numItems++
+		if numItems == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatal("Expected mining to stop, got ", err)
+	}
+	// Simulate being stopped while recording the next item.
+	for name, partial := range map[string]string{
+		checkpointItemsetsFile: "\x02\x01",
+		checkpointProgressFile: "4",
+	} {
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(partial)
+		file.Close()
+	}
+
+	resumed, err := Resume(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resumed.itemizer, uncheckpointed.itemizer) ||
+		!reflect.DeepEqual(resumed.frequency, uncheckpointed.frequency) ||
+		resumed.numTransactions != uncheckpointed.numTransactions {
+		t.Error("Resumed Context differs from the original")
+	}
+	cp, err := openMiningCheckpoint(dir, minCountFor(minSupport, resumed.numTransactions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.done) != 3 || cp.complete {
+		t.Error("Expected 3 items mined before resuming, got ", len(cp.done))
+	}
+	cp.close()
+
+	for i := 0; i < 2; i++ {
+		// The second time, the checkpoint is complete, and the itemsets are
+		// read from it without mining.
+		itemsets, err := resumed.GenerateItemsets(minSupport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(itemsets, expected) {
+			t.Error("Resumed mining generated different itemsets")
+		}
+	}
+
+	// A different minimum support starts mining again.
+	itemsets, err := resumed.GenerateItemsets(2 * minSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ = uncheckpointed.GenerateItemsets(2 * minSupport)
+	if !reflect.DeepEqual(itemsets, expected) {
+		t.Error("Mining at a new support generated different itemsets")
+	}
+}
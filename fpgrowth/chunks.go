@@ -0,0 +1,241 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// minChunkSize is the smallest number of bytes of a file read by each worker
+// in a parallel pass over it. Files smaller than two chunks are read by one
+// worker.
+var minChunkSize int64 = 4 << 20
+
+// chunk is a byte range [start, end) of a file, which contains whole lines.
+type chunk struct {
+	start int64
+	end   int64
+}
+
+// splitFile divides the file at path into at most n chunks of roughly equal
+// size. Every chunk except the last ends just after a newline.
+func splitFile(path string, n int) ([]chunk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if maxChunks := size / minChunkSize; int64(n) > maxChunks {
+		n = max(1, int(maxChunks))
+	}
+
+	chunks := make([]chunk, 0, n)
+	start := int64(0)
+	r := bufio.NewReader(nil)
+	for i := 1; i < n; i++ {
+		end := size * int64(i) / int64(n)
+		if end <= start {
+			continue
+		}
+		// Move end to just after the newline ending the line containing the
+		// byte before it.
+		r.Reset(io.NewSectionReader(file, end-1, size-end+1))
+		end--
+		for {
+			line, err := r.ReadSlice('\n')
+			end += int64(len(line))
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			break
+		}
+		if end >= size {
+			break
+		}
+		chunks = append(chunks, chunk{start: start, end: end})
+		start = end
+	}
+	return append(chunks, chunk{start: start, end: size}), nil
+}
+
+// forEachChunk reads the transactions in each of the chunks of the file at
+// path on its own goroutine, and calls fn with the index of the chunk and the
+// tokens and count of each transaction in it, as forEachTransaction does. If
+// reading any chunk fails, returns the error from the earliest such chunk.
+// The bytes read are added to progress.
+func forEachChunk(
+	path string,
+	weighted bool,
+	chunks []chunk,
+	progress *progressTracker,
+	fn func(chunk int, tokens []string, count int) error,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var r io.Reader = io.NewSectionReader(file, c.start, c.end-c.start)
+			if progress != nil {
+				r = progressReader{r: r, progress: progress}
+			}
+			line, err := scanTransactions(
+				r,
+				weighted,
+				func(tokens []string, count int) error {
+					return fn(i, tokens, count)
+				},
+			)
+			if err != nil && line > 0 {
+				before, countErr := countLines(file, c.start)
+				if countErr != nil {
+					err = countErr
+				} else {
+					err = fmt.Errorf("%s:%d: %w", path, before+line, err)
+				}
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countLines returns the number of newlines in the first n bytes of file.
+func countLines(file *os.File, n int64) (int, error) {
+	lines := 0
+	buf := make([]byte, 64*1024)
+	r := io.NewSectionReader(file, 0, n)
+	for {
+		read, err := r.Read(buf)
+		lines += bytes.Count(buf[:read], []byte{'\n'})
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// chunkItems counts the items in a chunk of a file. Each chunk has its own
+// dictionary of items, so that chunks can be counted in parallel, and then
+// merged into an Itemizer.
+type chunkItems struct {
+	ids map[string]int
+	// tokens stores the chunk's items in order of first occurrence, and counts
+	// the number of occurrences of each.
+	tokens          []string
+	counts          []int
+	numTransactions int
+	// lengths counts the transactions of each length.
+	lengths []int
+}
+
+func newChunkItems() *chunkItems {
+	return &chunkItems{
+		ids:    make(map[string]int),
+		tokens: make([]string, 0),
+		counts: make([]int, 0),
+	}
+}
+
+func (c *chunkItems) add(tokens []string, count int) {
+	c.numTransactions += count
+	length := 0
+	for _, token := range tokens {
+		token = trimToken(token)
+		if len(token) == 0 {
+			continue
+		}
+		length++
+		id, found := c.ids[token]
+		if !found {
+			id = len(c.tokens)
+			c.ids[token] = id
+			c.tokens = append(c.tokens, token)
+			c.counts = append(c.counts, 0)
+		}
+		c.counts[id] += count
+	}
+	addLength(&c.lengths, length, count)
+}
+
+// addLength adds count transactions of length to the histogram lengths.
+func addLength(lengths *[]int, length int, count int) {
+	*lengths = ensureInBounds(*lengths, length)
+	(*lengths)[length] += count
+}
+
+// countChunks adds the item frequencies of the dataset at path to frequency,
+// and the number of transactions of each length to lengths, and returns its
+// number of transactions, reading up to parallelism chunks of the file in
+// parallel. The chunks' items are merged into itemizer in order,
+// so items are numbered as if the file was read sequentially.
+func countChunks(
+	c context.Context,
+	path string,
+	weighted bool,
+	parallelism int,
+	progress *progressTracker,
+	itemizer *Itemizer,
+	frequency *itemCount,
+	lengths *[]int,
+) (int, error) {
+	chunks, err := splitFile(path, parallelism)
+	if err != nil {
+		return 0, err
+	}
+	counts := make([]*chunkItems, len(chunks))
+	for i := range counts {
+		counts[i] = newChunkItems()
+	}
+	err = forEachChunk(path, weighted, chunks, progress, func(i int, tokens []string, count int) error {
+		if cancelled(c) {
+			return c.Err()
+		}
+		counts[i].add(tokens, count)
+		return nil
+	})
+	if c.Err() != nil {
+		return 0, c.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	numTransactions := 0
+	for _, c := range counts {
+		numTransactions += c.numTransactions
+		for length, count := range c.lengths {
+			addLength(lengths, length, count)
+		}
+		for id, token := range c.tokens {
+			frequency.increment(itemizer.item(token), c.counts[id])
+		}
+	}
+	return numTransactions, nil
+}
@@ -0,0 +1,89 @@
+package fpgrowth
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitFile(t *testing.T) {
+	defer func(size int64) { minChunkSize = size }(minChunkSize)
+	minChunkSize = 1
+	lines := randomTransactions(100, 7)
+	lines[40] = strings.Repeat("x,", 5000) + "y"
+	path := writeTestCsv(t, lines...)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{1, 2, 3, 8, 1000} {
+		chunks, err := splitFile(path, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chunks) == 0 || len(chunks) > n {
+			t.Error("Expected between 1 and ", n, " chunks, got ", len(chunks))
+			continue
+		}
+		start := int64(0)
+		for i, c := range chunks {
+			if c.start != start || c.end <= c.start {
+				t.Error("Chunks ", chunks, " don't cover file")
+			}
+			if i+1 < len(chunks) && contents[c.end-1] != '\n' {
+				t.Error("Chunk ", c, " doesn't end at a newline")
+			}
+			start = c.end
+		}
+		if start != int64(len(contents)) {
+			t.Error("Chunks ", chunks, " don't cover file of size ", len(contents))
+		}
+	}
+}
+
+func TestParallelReading(t *testing.T) {
+	defer func(size int64) { minChunkSize = size }(minChunkSize)
+	minChunkSize = 64
+	path := writeTestCsv(t, randomTransactions(500, 8)...)
+	sequential, err := Init(path, WithParallelism(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks, _ := splitFile(path, 4); len(chunks) != 4 {
+		t.Fatal("Expected input to be split into 4 chunks, got ", len(chunks))
+	}
+	parallel, err := Init(path, WithParallelism(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parallel.itemizer, sequential.itemizer) {
+		t.Error("Items numbered differently when read in parallel")
+	}
+	if !reflect.DeepEqual(parallel.frequency, sequential.frequency) ||
+		parallel.numTransactions != sequential.numTransactions {
+		t.Error("Item frequencies differ when read in parallel")
+	}
+	expected, err := FPGrowth{}.Mine(sequential, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed, err := FPGrowth{}.Mine(parallel, 0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Error("Itemsets differ when read in parallel")
+	}
+}
+
+func TestParallelReadingError(t *testing.T) {
+	defer func(size int64) { minChunkSize = size }(minChunkSize)
+	minChunkSize = 16
+	lines := repeatLines("2,a,b", 50)
+	lines[37] = "x,a,b"
+	_, err := Init(writeTestCsv(t, lines...), WithWeightedTransactions(), WithParallelism(4))
+	if err == nil || !strings.Contains(err.Error(), ":38: invalid transaction count") {
+		t.Error("Expected error on line 38, got ", err)
+	}
+}
@@ -0,0 +1,350 @@
+package fpgrowth
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// GenerateClassRules generates class association rules from itemsets, with
+// confidence/lift above minConfidence/minLift. A class association rule's
+// consequent is a single one of the class items, and its antecedent contains
+// no class items. Class items which don't occur in the dataset are ignored.
+func (ctx Context) GenerateClassRules(
+	itemsets GeneratedItemsets,
+	classes []string,
+	minConfidence float64,
+	minLift float64,
+) []Rule {
+	return ctx.generateClassRules(itemsets, classes, func(Item) float64 {
+		return 0
+	}, minConfidence, minLift)
+}
+
+// ClassMinSupports returns a minimum support for the rules predicting each of
+// classes, which is minSupport scaled by the class's support, as in msCBA. A
+// single minimum support finds no rules for rare classes, so the classifier
+// never predicts them. Itemsets should be generated at the lowest of the
+// returned supports. Classes which don't occur in the dataset are omitted.
+func (ctx Context) ClassMinSupports(
+	classes []string,
+	minSupport float64,
+) map[string]float64 {
+	minSupports := make(map[string]float64)
+	for class := range ctx.itemizer.lookupAll(classes) {
+		support := float64(ctx.frequency.get(class)) / float64(ctx.numTransactions)
+		minSupports[ctx.itemizer.ToStr(class)] = minSupport * support
+	}
+	return minSupports
+}
+
+// GenerateClassRulesWithMinSupports is GenerateClassRules, but only generates
+// rules predicting each class in minSupports with support of at least its
+// minimum support, such as those returned by ClassMinSupports().
+func (ctx Context) GenerateClassRulesWithMinSupports(
+	itemsets GeneratedItemsets,
+	minSupports map[string]float64,
+	minConfidence float64,
+	minLift float64,
+) []Rule {
+	classes := make([]string, 0, len(minSupports))
+	for class := range minSupports {
+		classes = append(classes, class)
+	}
+	return ctx.generateClassRules(itemsets, classes, func(class Item) float64 {
+		return minSupports[ctx.itemizer.ToStr(class)]
+	}, minConfidence, minLift)
+}
+
+func (ctx Context) generateClassRules(
+	itemsets GeneratedItemsets,
+	classes []string,
+	minSupport func(class Item) float64,
+	minConfidence float64,
+	minLift float64,
+) []Rule {
+	isClass := ctx.itemizer.lookupAll(classes)
+	itemsetSupport := createSupportLookup(itemsets, ctx.numTransactions)
+	rules := make([]Rule, 0)
+	for _, itemset := range itemsets {
+		if len(itemset.Itemset) < 2 {
+			continue
+		}
+		var class Item
+		numClasses := 0
+		for _, item := range itemset.Itemset {
+			if isClass[item] {
+				class = item
+				numClasses++
+			}
+		}
+		if numClasses != 1 {
+			continue
+		}
+		antecedent, consequent := without(itemset.Itemset, class)
+		support := float64(itemset.Count) / float64(ctx.numTransactions)
+		if support < minSupport(class) {
+			continue
+		}
+		confidence, lift, found := makeStats(
+			antecedent,
+			consequent,
+			support,
+			itemsetSupport,
+		)
+		if !found || confidence < minConfidence || lift < minLift {
+			continue
+		}
+		rules = append(
+			rules,
+			NewRule(antecedent, consequent, support, confidence, lift),
+		)
+	}
+	return rules
+}
+
+// Classifier predicts the class of a transaction from its items, using an
+// ordered list of class association rules and a default class.
+type Classifier struct {
+	itemizer     Itemizer
+	rules        []Rule
+	defaultClass Item
+	isClass      map[Item]bool
+}
+
+// Evaluation reports the accuracy of a Classifier on a holdout dataset.
+type Evaluation struct {
+	// Accuracy is the proportion of transactions classified correctly.
+	Accuracy float64
+	// Classes names the rows and columns of Confusion.
+	Classes []string
+	// Confusion counts transactions by actual class (first index) and
+	// predicted class (second index).
+	Confusion [][]int
+}
+
+type labelledTransaction struct {
+	items []Item
+	class Item
+	count int
+}
+
+// precedes returns whether rule a takes precedence over rule b; higher
+// confidence first, then higher support, then fewer antecedent items.
+func precedes(a, b *Rule) bool {
+	if a.Confidence != b.Confidence {
+		return a.Confidence > b.Confidence
+	}
+	if a.Support != b.Support {
+		return a.Support > b.Support
+	}
+	return len(a.Antecedent) < len(b.Antecedent)
+}
+
+func covers(rule *Rule, items []Item) bool {
+	return intersectionSize(rule.Antecedent, items) == len(rule.Antecedent)
+}
+
+// labelTransaction splits the class item out of a transaction's items.
+// Returns false if the transaction doesn't have exactly one class item.
+func labelTransaction(
+	items []Item,
+	isClass map[Item]bool,
+	count int,
+) (labelledTransaction, bool) {
+	sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+	items = dedupeSorted(items)
+	lt := labelledTransaction{items: make([]Item, 0, len(items)), count: count}
+	numClasses := 0
+	for _, item := range items {
+		if isClass[item] {
+			lt.class = item
+			numClasses++
+		} else {
+			lt.items = append(lt.items, item)
+		}
+	}
+	return lt, numClasses == 1
+}
+
+func majorityClass(classCounts map[Item]int) (Item, int) {
+	best, bestCount := invalidItem, -1
+	for class, count := range classCounts {
+		if count > bestCount || (count == bestCount && class < best) {
+			best, bestCount = class, count
+		}
+	}
+	return best, bestCount
+}
+
+// BuildClassifier builds a Classifier from class association rules, using
+// CBA's database coverage heuristic over the Context's dataset. Rules are
+// ordered by precedence, and a rule is kept only if it correctly classifies
+// at least one training transaction not covered by a previous rule. The rule
+// list is then truncated where the total number of training errors, including
+// those of the default class, is lowest. Each rule's consequent must be one of
+// classes.
+func (ctx Context) BuildClassifier(
+	rules []Rule,
+	classes []string,
+) (*Classifier, error) {
+	isClass := ctx.itemizer.lookupAll(classes)
+	if len(isClass) == 0 {
+		return nil, errors.New("none of the classes occur in the dataset")
+	}
+	for _, rule := range rules {
+		if len(rule.Consequent) != 1 || !isClass[rule.Consequent[0]] {
+			return nil, errors.New("rule consequent isn't a single class item")
+		}
+	}
+	training := make([]labelledTransaction, 0)
+	classCounts := make(map[Item]int)
+	remaining := 0
+	err := ctx.forEachTransaction(context.Background(), func(tokens []string, count int) error {
+		items := ctx.itemizer.lookup(tokens)
+		if lt, ok := labelTransaction(items, isClass, count); ok {
+			training = append(training, lt)
+			classCounts[lt.class] += count
+			remaining += count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(training) == 0 {
+		return nil, errors.New("no transactions have exactly one class item")
+	}
+
+	ordered := make([]Rule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return precedes(&ordered[i], &ordered[j])
+	})
+
+	defaultClass, defaultCount := majorityClass(classCounts)
+	bestErrors := remaining - defaultCount
+	bestLen, bestDefault := 0, defaultClass
+
+	selected := make([]Rule, 0)
+	covered := make([]bool, len(training))
+	ruleErrors := 0
+	for i := range ordered {
+		rule := &ordered[i]
+		class := rule.Consequent[0]
+		correct := false
+		for t := range training {
+			if !covered[t] && training[t].class == class && covers(rule, training[t].items) {
+				correct = true
+				break
+			}
+		}
+		if !correct {
+			continue
+		}
+		selected = append(selected, *rule)
+		for t := range training {
+			if covered[t] || !covers(rule, training[t].items) {
+				continue
+			}
+			covered[t] = true
+			if training[t].class != class {
+				ruleErrors += training[t].count
+			}
+			classCounts[training[t].class] -= training[t].count
+			remaining -= training[t].count
+		}
+		defaultErrors := 0
+		if remaining > 0 {
+			defaultClass, defaultCount = majorityClass(classCounts)
+			defaultErrors = remaining - defaultCount
+		}
+		if ruleErrors+defaultErrors < bestErrors {
+			bestErrors = ruleErrors + defaultErrors
+			bestLen, bestDefault = len(selected), defaultClass
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+
+	return &Classifier{
+		itemizer:     ctx.itemizer,
+		rules:        selected[:bestLen],
+		defaultClass: bestDefault,
+		isClass:      isClass,
+	}, nil
+}
+
+// Rules returns the classifier's rules, in the order they're applied.
+func (c *Classifier) Rules() []Rule {
+	return c.rules
+}
+
+// Predict returns the class of the first rule whose antecedent is contained
+// in items, or the default class if no rule matches. Items which weren't in
+// the training dataset are ignored.
+func (c *Classifier) Predict(items []string) string {
+	return c.itemizer.ToStr(c.predict(c.itemizer.lookup(items)))
+}
+
+func (c *Classifier) predict(items []Item) Item {
+	sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+	items = dedupeSorted(items)
+	for i := range c.rules {
+		if covers(&c.rules[i], items) {
+			return c.rules[i].Consequent[0]
+		}
+	}
+	return c.defaultClass
+}
+
+// Evaluate classifies each transaction in the holdout dataset at path, whose
+// transactions contain their actual class item, and reports the accuracy and
+// confusion matrix. Transactions without exactly one known class item are
+// skipped.
+func (c *Classifier) Evaluate(holdoutCsvPath string, opts ...Option) (Evaluation, error) {
+	o := makeOptions(opts)
+	classes := make([]Item, 0, len(c.isClass))
+	for class := range c.isClass {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return c.itemizer.cmp(classes[i], classes[j])
+	})
+	index := make(map[Item]int)
+	eval := Evaluation{
+		Classes:   make([]string, len(classes)),
+		Confusion: make([][]int, len(classes)),
+	}
+	for i, class := range classes {
+		index[class] = i
+		eval.Classes[i] = c.itemizer.ToStr(class)
+		eval.Confusion[i] = make([]int, len(classes))
+	}
+
+	correct, total := 0, 0
+	err := forEachTransaction(holdoutCsvPath, o.weighted, func(tokens []string, count int) error {
+		lt, ok := labelTransaction(c.itemizer.lookup(tokens), c.isClass, count)
+		if !ok {
+			return nil
+		}
+		predicted, ok := index[c.predict(lt.items)]
+		if !ok {
+			return errors.New("predicted item isn't a class")
+		}
+		eval.Confusion[index[lt.class]][predicted] += count
+		if predicted == index[lt.class] {
+			correct += count
+		}
+		total += count
+		return nil
+	})
+	if err != nil {
+		return Evaluation{}, err
+	}
+	if total > 0 {
+		eval.Accuracy = float64(correct) / float64(total)
+	}
+	return eval, nil
+}
@@ -0,0 +1,135 @@
+package fpgrowth
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestClassifier(t *testing.T) {
+	train := writeTestCsv(t, append(append(append(append(
+		repeatLines("x,yes", 3),
+		repeatLines("y,no", 3)...),
+		"x,y,yes"),
+		repeatLines("z,w,no", 2)...),
+		repeatLines("w,yes", 1)...)...,
+	)
+	ctx, err := Init(train)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := ctx.GenerateItemsets(0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes := []string{"yes", "no", "maybe"}
+	rules := ctx.GenerateClassRules(itemsets, classes, 0.6, 0)
+	for _, rule := range rules {
+		consequent := ctx.itemizer.ToStr(rule.Consequent[0])
+		if len(rule.Consequent) != 1 || (consequent != "yes" && consequent != "no") {
+			t.Error("Consequent isn't a class ", rule)
+		}
+		for _, item := range rule.Antecedent {
+			if s := ctx.itemizer.ToStr(item); s == "yes" || s == "no" {
+				t.Error("Antecedent contains a class ", rule)
+			}
+		}
+	}
+
+	if _, err := ctx.BuildClassifier(ctx.GenerateRules(itemsets, 0, 0), classes); err == nil {
+		t.Error("Expected rules whose consequents aren't classes to be rejected")
+	}
+	classifier, err := ctx.BuildClassifier(rules, classes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	predictions := map[string]string{
+		"x":       "yes",
+		"y":       "no",
+		"x,y":     "yes",
+		"z":       "no",
+		"unknown": "yes",
+	}
+	for items, expected := range predictions {
+		if c := classifier.Predict(strings.Split(items, ",")); c != expected {
+			t.Error("Predicted ", c, " for ", items, ", expected ", expected)
+		}
+	}
+
+	holdout := writeTestCsv(t,
+		"x,yes",
+		"y,no",
+		"y,yes",
+		"z,no",
+		"q",
+	)
+	eval, err := classifier.Evaluate(holdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eval.Accuracy != 0.75 {
+		t.Error("Expected accuracy 0.75, got ", eval.Accuracy)
+	}
+	// Classes are sorted; "no" then "yes".
+	expected := [][]int{{2, 0}, {1, 1}}
+	for i := range expected {
+		for j := range expected[i] {
+			if eval.Confusion[i][j] != expected[i][j] {
+				t.Error("Expected confusion ", expected, " got ", eval.Confusion)
+			}
+		}
+	}
+}
+
+func TestClassifierImbalancedClasses(t *testing.T) {
+	train := writeTestCsv(t, append(append(append(
+		repeatLines("x,common", 8),
+		repeatLines("y,common", 6)...),
+		repeatLines("z,common", 4)...),
+		repeatLines("r,rare", 2)...)...,
+	)
+	ctx, err := Init(train)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classes := []string{"common", "rare"}
+	const minSupport = 0.3
+
+	// A single minimum support finds no rules predicting the rare class.
+	itemsets, err := ctx.GenerateItemsets(minSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier, err := ctx.BuildClassifier(ctx.GenerateClassRules(itemsets, classes, 0.5, 0), classes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := classifier.Predict([]string{"r"}); c != "common" {
+		t.Error("Expected the default class without scaled supports, got ", c)
+	}
+
+	minSupports := ctx.ClassMinSupports(classes, minSupport)
+	if math.Abs(minSupports["common"]-0.27) > 1e-9 || math.Abs(minSupports["rare"]-0.03) > 1e-9 {
+		t.Error("Unexpected class minimum supports ", minSupports)
+	}
+	itemsets, err = ctx.GenerateItemsets(minSupports["rare"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := ctx.GenerateClassRulesWithMinSupports(itemsets, minSupports, 0.5, 0)
+	for _, rule := range rules {
+		class := ctx.itemizer.ToStr(rule.Consequent[0])
+		if rule.Support < minSupports[class] {
+			t.Error("Rule ", rule, " is below its class's minimum support")
+		}
+	}
+	classifier, err = ctx.BuildClassifier(rules, classes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for items, expected := range map[string]string{"r": "rare", "x": "common", "z": "common"} {
+		if c := classifier.Predict([]string{items}); c != expected {
+			t.Error("Predicted ", c, " for ", items, ", expected ", expected)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ContrastContext stores context for comparing two datasets, for example
+// baskets from two regions or two time periods. Both datasets share an
+// Itemizer, so the same Item represents the same string in each.
+type ContrastContext struct {
+	baseline   Context
+	comparison Context
+}
+
+// ContrastItemset is an itemset that's frequent in at least one of the
+// datasets, along with its support in each.
+type ContrastItemset struct {
+	Itemset           []Item
+	BaselineSupport   float64
+	ComparisonSupport float64
+}
+
+// GrowthRate returns the ratio of the itemset's support in the comparison
+// dataset to its support in the baseline dataset. Returns +Inf if the itemset
+// doesn't occur in the baseline.
+func (ci ContrastItemset) GrowthRate() float64 {
+	if ci.BaselineSupport == 0 {
+		return math.Inf(1)
+	}
+	return ci.ComparisonSupport / ci.BaselineSupport
+}
+
+// Difference returns the itemset's support in the comparison dataset minus
+// its support in the baseline dataset.
+func (ci ContrastItemset) Difference() float64 {
+	return ci.ComparisonSupport - ci.BaselineSupport
+}
+
+// RuleChange describes how an association rule's support, confidence and
+// lift differ between the baseline and comparison datasets. Both rules have
+// the same antecedent and consequent.
+type RuleChange struct {
+	Baseline   Rule
+	Comparison Rule
+}
+
+// InitContrast creates a ContrastContext, performing the first pass over
+// both datasets. opts apply to both datasets, so can't include
+// WithCheckpoint() or WithSample().
+func InitContrast(
+	baselineCsvPath string,
+	comparisonCsvPath string,
+	opts ...Option,
+) (ContrastContext, error) {
+	o := makeOptions(opts)
+	if len(o.checkpointDir) > 0 || o.sampleSize > 0 {
+		return ContrastContext{}, errors.New("checkpoints and sampling can't be used when contrasting datasets")
+	}
+	baseline := newContext(o)
+	if err := baseline.AddTransactions(baselineCsvPath); err != nil {
+		return ContrastContext{}, err
+	}
+	comparison := newContext(o)
+	comparison.itemizer = baseline.itemizer
+	if err := comparison.AddTransactions(comparisonCsvPath); err != nil {
+		return ContrastContext{}, err
+	}
+	// The Itemizers share their maps, but the comparison's has the up to date
+	// count of items.
+	baseline.itemizer = comparison.itemizer
+	return ContrastContext{baseline: baseline, comparison: comparison}, nil
+}
+
+// Baseline returns the Context of the baseline dataset.
+func (ctx ContrastContext) Baseline() Context {
+	return ctx.baseline
+}
+
+// Comparison returns the Context of the comparison dataset.
+func (ctx ContrastContext) Comparison() Context {
+	return ctx.comparison
+}
+
+// ContrastItemsets stores the itemsets frequent in either dataset, along
+// with what's needed to generate rules from them.
+type ContrastItemsets struct {
+	Itemsets          []ContrastItemset
+	baseline          GeneratedItemsets
+	comparison        GeneratedItemsets
+	baselineSupport   *itemsetSupportLookup
+	comparisonSupport *itemsetSupportLookup
+}
+
+// GenerateContrastItemsets generates the itemsets with support above
+// minSupport in either dataset. Itemsets which are frequent in only one
+// dataset are counted in the other with an extra pass, so that every
+// ContrastItemset has its exact support in both.
+func (ctx ContrastContext) GenerateContrastItemsets(
+	minSupport float64,
+) (ContrastItemsets, error) {
+	baseline, err := ctx.baseline.GenerateItemsets(minSupport)
+	if err != nil {
+		return ContrastItemsets{}, err
+	}
+	comparison, err := ctx.comparison.GenerateItemsets(minSupport)
+	if err != nil {
+		return ContrastItemsets{}, err
+	}
+	baselineAll, err := countMissing(ctx.baseline, baseline, comparison)
+	if err != nil {
+		return ContrastItemsets{}, err
+	}
+	comparisonAll, err := countMissing(ctx.comparison, comparison, baseline)
+	if err != nil {
+		return ContrastItemsets{}, err
+	}
+	baselineSupport := createSupportLookup(
+		baselineAll,
+		ctx.baseline.numTransactions,
+	)
+	comparisonSupport := createSupportLookup(
+		comparisonAll,
+		ctx.comparison.numTransactions,
+	)
+
+	itemsets := make([]ContrastItemset, 0, len(baselineAll))
+	for _, iwc := range baselineAll {
+		// Both datasets have counts for the same itemsets.
+		bSup, _ := baselineSupport.lookup(iwc.Itemset)
+		cSup, _ := comparisonSupport.lookup(iwc.Itemset)
+		itemsets = append(itemsets, ContrastItemset{
+			Itemset:           iwc.Itemset,
+			BaselineSupport:   bSup,
+			ComparisonSupport: cSup,
+		})
+	}
+	return ContrastItemsets{
+		Itemsets:          itemsets,
+		baseline:          baseline,
+		comparison:        comparison,
+		baselineSupport:   baselineSupport,
+		comparisonSupport: comparisonSupport,
+	}, nil
+}
+
+// countMissing returns the counts in ctx's dataset of the itemsets that are
+// frequent in either dataset. Itemsets in frequent already have their counts;
+// itemsets only in other are counted with a pass over ctx's dataset.
+func countMissing(
+	ctx Context,
+	frequent GeneratedItemsets,
+	other GeneratedItemsets,
+) ([]ItemsetWithCount, error) {
+	known := createSupportLookup(frequent, 1)
+	missing := make([][]Item, 0)
+	for _, iwc := range other {
+		if !known.table.contains(iwc.Itemset) {
+			missing = append(missing, iwc.Itemset)
+		}
+	}
+	counts, err := ctx.countItemsets(context.Background(), missing)
+	if err != nil {
+		return nil, err
+	}
+	all := make([]ItemsetWithCount, 0, len(frequent)+len(missing))
+	all = append(all, frequent...)
+	for i, itemset := range missing {
+		all = append(all, ItemsetWithCount{Itemset: itemset, Count: counts[i]})
+	}
+	return all, nil
+}
+
+// EmergingPatterns returns the itemsets whose support changed between the
+// datasets by at least a factor of minGrowthRate, or by at least
+// minDifference, in either direction. A threshold of zero is ignored.
+func (ci ContrastItemsets) EmergingPatterns(
+	minGrowthRate float64,
+	minDifference float64,
+) []ContrastItemset {
+	patterns := make([]ContrastItemset, 0)
+	for _, itemset := range ci.Itemsets {
+		emerging := false
+		if minGrowthRate > 0 {
+			rate := itemset.GrowthRate()
+			emerging = rate >= minGrowthRate || rate*minGrowthRate <= 1
+		}
+		if minDifference > 0 {
+			emerging = emerging || math.Abs(itemset.Difference()) >= minDifference
+		}
+		if emerging {
+			patterns = append(patterns, itemset)
+		}
+	}
+	return patterns
+}
+
+func statsFor(a, c []Item, support *itemsetSupportLookup) Rule {
+	acSup, _ := support.lookup(union(a, c))
+	aSup, _ := support.lookup(a)
+	cSup, _ := support.lookup(c)
+	rule := NewRule(a, c, acSup, 0, 0)
+	if aSup > 0 && cSup > 0 {
+		rule.Confidence = acSup / aSup
+		rule.Lift = rule.Confidence / cSup
+	}
+	return rule
+}
+
+// GenerateRuleChanges generates the association rules with confidence/lift
+// above minConfidence/minLift in either dataset, annotated with their
+// support, confidence and lift in both datasets.
+func (ctx ContrastContext) GenerateRuleChanges(
+	itemsets ContrastItemsets,
+	minConfidence float64,
+	minLift float64,
+) []RuleChange {
+	seen := make(map[string]bool)
+	changes := make([]RuleChange, 0)
+	add := func(rules []Rule) {
+		for _, rule := range rules {
+			key := sequenceKey([][]Item{rule.Antecedent, rule.Consequent})
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			changes = append(changes, RuleChange{
+				Baseline:   statsFor(rule.Antecedent, rule.Consequent, itemsets.baselineSupport),
+				Comparison: statsFor(rule.Antecedent, rule.Consequent, itemsets.comparisonSupport),
+			})
+		}
+	}
+	add(ctx.baseline.GenerateRules(itemsets.baseline, minConfidence, minLift))
+	add(ctx.comparison.GenerateRules(itemsets.comparison, minConfidence, minLift))
+	return changes
+}
+
+// WriteEmergingPatterns writes contrast itemsets to CSV file.
+func (ctx ContrastContext) WriteEmergingPatterns(
+	patterns []ContrastItemset,
+	filePath string,
+) error {
+	output, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Itemset,BaselineSupport,ComparisonSupport,GrowthRate,Difference")
+	for _, p := range patterns {
+		writeItemset(w, &ctx.baseline.itemizer, p.Itemset)
+		fmt.Fprintf(
+			w,
+			",%f,%f,%f,%f\n",
+			p.BaselineSupport,
+			p.ComparisonSupport,
+			p.GrowthRate(),
+			p.Difference(),
+		)
+	}
+	return w.Flush()
+}
+
+// WriteRuleChanges writes rule changes to CSV file.
+func (ctx ContrastContext) WriteRuleChanges(
+	outputPath string,
+	changes []RuleChange,
+) error {
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Antecedent => Consequent,BaselineConfidence,ComparisonConfidence,"+
+		"BaselineLift,ComparisonLift,BaselineSupport,ComparisonSupport")
+	for _, change := range changes {
+		writeItemset(w, &ctx.baseline.itemizer, change.Baseline.Antecedent)
+		fmt.Fprint(w, " => ")
+		writeItemset(w, &ctx.baseline.itemizer, change.Baseline.Consequent)
+		fmt.Fprintf(
+			w,
+			",%f,%f,%f,%f,%f,%f\n",
+			change.Baseline.Confidence,
+			change.Comparison.Confidence,
+			change.Baseline.Lift,
+			change.Comparison.Lift,
+			change.Baseline.Support,
+			change.Comparison.Support,
+		)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,104 @@
+package fpgrowth
+
+import (
+	"math"
+	"testing"
+)
+
+func repeatLines(line string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = line
+	}
+	return lines
+}
+
+func TestContrast(t *testing.T) {
+	baseline := writeTestCsv(t, append(append(
+		repeatLines("a,b", 4),
+		repeatLines("a", 4)...),
+		repeatLines("c", 2)...)...,
+	)
+	comparison := writeTestCsv(t, append(append(
+		repeatLines("a,b", 1),
+		repeatLines("a", 3)...),
+		repeatLines("c", 6)...)...,
+	)
+	ctx, err := InitContrast(baseline, comparison)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := ctx.GenerateContrastItemsets(0.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := func(itemset []Item) string {
+		s := ""
+		for _, item := range itemset {
+			s += ctx.baseline.itemizer.ToStr(item)
+		}
+		return s
+	}
+	expected := map[string][2]float64{
+		"a":  {0.8, 0.4},
+		"b":  {0.4, 0.1},
+		"ab": {0.4, 0.1},
+		"c":  {0.2, 0.6},
+	}
+	if len(itemsets.Itemsets) != len(expected) {
+		t.Error("Expected ", len(expected), " itemsets, got ", itemsets.Itemsets)
+	}
+	for _, ci := range itemsets.Itemsets {
+		e := expected[name(ci.Itemset)]
+		if math.Abs(ci.BaselineSupport-e[0]) > 1e-9 ||
+			math.Abs(ci.ComparisonSupport-e[1]) > 1e-9 {
+			t.Error("Itemset ", name(ci.Itemset), " expected ", e, " got ", ci)
+		}
+	}
+
+	names := func(patterns []ContrastItemset) map[string]int {
+		m := make(map[string]int)
+		for _, p := range patterns {
+			m[name(p.Itemset)] = 1
+		}
+		return m
+	}
+	growth := names(itemsets.EmergingPatterns(2.9, 0))
+	if !equalCounts(growth, map[string]int{"b": 1, "ab": 1, "c": 1}) {
+		t.Error("Unexpected emerging patterns by growth rate ", growth)
+	}
+	difference := names(itemsets.EmergingPatterns(0, 0.35))
+	if !equalCounts(difference, map[string]int{"a": 1, "c": 1}) {
+		t.Error("Unexpected emerging patterns by difference ", difference)
+	}
+
+	changes := ctx.GenerateRuleChanges(itemsets, 0.5, 1)
+	if len(changes) != 2 {
+		t.Fatal("Expected 2 rule changes, got ", changes)
+	}
+	for _, change := range changes {
+		b, c := change.Baseline, change.Comparison
+		var e [4]float64
+		switch name(b.Antecedent) {
+		case "a":
+			e = [4]float64{0.5, 1.25, 0.25, 2.5}
+		case "b":
+			e = [4]float64{1, 1.25, 1, 2.5}
+		}
+		if math.Abs(b.Confidence-e[0]) > 1e-9 || math.Abs(b.Lift-e[1]) > 1e-9 ||
+			math.Abs(c.Confidence-e[2]) > 1e-9 || math.Abs(c.Lift-e[3]) > 1e-9 {
+			t.Error("Unexpected rule change ", change, " expected ", e)
+		}
+	}
+}
+
+func TestContrastRejectsSharedOptions(t *testing.T) {
+	baseline := writeTestCsv(t, "a,b", "a")
+	comparison := writeTestCsv(t, "a", "c")
+	for _, opt := range []Option{WithCheckpoint(t.TempDir()), WithSample(10, 1)} {
+		if _, err := InitContrast(baseline, comparison, opt); err == nil {
+			t.Error("Expected InitContrast to reject an option for one dataset")
+		}
+	}
+}
@@ -5,7 +5,10 @@
 // items into ints, and internally storing itemsets sorted to ensure fast
 // access and comparisons.
 //
-// Input datasets must be in CSV format, without header rows.
+// Input datasets must be in CSV format, without header rows. Pre-aggregated
+// datasets, where the first column of each line is the number of times that
+// transaction occurs, can be read by passing WithWeightedTransactions() to
+// Init().
 //
 // To generate association rules, you must create an fpgrowth.Context struct
 // by calling the fpgrowth.Init() method. This performs the first pass to count
@@ -13,53 +16,103 @@
 // fpgrowth.GenerateItemsets() to find the frequent itemsets, and pass that to
 // fpgrowth.GenerateRules() to extract the association rules those itemsets
 // generate. The itemsets and rules can be written to disk with WriteItemsets()
-// and WriteRules() respectively.
+// and WriteRules() respectively. Other frequent itemset mining algorithms,
+// such as Apriori and Eclat, are available as implementations of the Miner
+// interface. Long running generation can be stopped by passing a
+// context.Context to GenerateItemsetsContext(), GenerateRulesContext() or a
+// Miner's MineContext(), and cancelling it. To follow their progress, pass
+// WithProgress() to Init(), and to log structured records of each phase, pass
+// WithLogger(); by default nothing is reported.
+//
+// When the itemsets or rules won't fit in memory, pass WithMemoryBudget() to
+// Init() and call GenerateSpilledItemsets(), which spills itemsets beyond the
+// budget to disk. ForEachRule() and WriteSpilledRules() then generate rules
+// from them without holding the rules in memory. To be able to resume long
+// jobs which are stopped, pass WithCheckpoint() to Init(), and restore the
+// Context with Resume().
+//
+// For datasets which grow over time, pass WithIncrementalMining() to Init()
+// and add new batches of transactions with Context.AddTransactions(), rather
+// than mining the whole history again. To mine the most recent transactions
+// of a live stream, use a StreamMiner, and call its Snapshot() method to get a
+// Context of the transactions currently in its window. For approximate
+// counts over an unbounded stream in bounded memory, use a LossyCounter.
 package fpgrowth
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"sort"
-	"strings"
+	"time"
 )
 
 // Item represents an item. Use the Itemizer struct to convert back to string
 // representation.
 type Item int
 
+// writeItemset writes the string form of the items in itemset, separated by
+// spaces.
+func writeItemset(w io.Writer, itemizer *Itemizer, itemset []Item) {
+	for i, item := range itemset {
+		if i != 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, itemizer.ToStr(item))
+	}
+}
+
 // WriteItemsets writes itemsets to CSV file.
 func (ctx Context) WriteItemsets(
 	itemsets GeneratedItemsets,
 	filePath string,
+) error {
+	return ctx.WriteItemsetsContext(context.Background(), itemsets, filePath)
+}
+
+// WriteItemsetsContext is like WriteItemsets, but stops and returns c.Err()
+// soon after c is cancelled, leaving the file incomplete.
+func (ctx Context) WriteItemsetsContext(
+	c context.Context,
+	itemsets GeneratedItemsets,
+	filePath string,
 ) error {
 	output, err := os.Create(filePath)
 	if err != nil {
 		return err
 	}
+	defer output.Close()
 	w := bufio.NewWriter(output)
 	fmt.Fprintln(w, "Itemset,Support")
 	n := float64(ctx.numTransactions)
 	for _, iwc := range itemsets {
-		first := true
-		for _, item := range iwc.Itemset {
-			if !first {
-				fmt.Fprintf(w, " ")
-			}
-			first = false
-			fmt.Fprint(w, ctx.itemizer.ToStr(item))
+		if cancelled(c) {
+			return c.Err()
 		}
+		writeItemset(w, &ctx.itemizer, iwc.Itemset)
 		fmt.Fprintf(w, " %f\n", float64(iwc.Count)/n)
 	}
-	w.Flush()
-	return nil
+	return w.Flush()
 }
 
 // WriteRules writes rules to CSV file.
 func (ctx Context) WriteRules(
 	outputPath string,
 	rules []Rule,
+) error {
+	return ctx.WriteRulesContext(context.Background(), outputPath, rules)
+}
+
+// WriteRulesContext is like WriteRules, but stops and returns c.Err() soon
+// after c is cancelled, leaving the file incomplete.
+func (ctx Context) WriteRulesContext(
+	c context.Context,
+	outputPath string,
+	rules []Rule,
 ) error {
 	output, err := os.Create(outputPath)
 	if err != nil {
@@ -68,19 +121,12 @@ func (ctx Context) WriteRules(
 	w := bufio.NewWriter(output)
 	fmt.Fprintln(w, "Antecedent => Consequent,Confidence,Lift,Support")
 	for _, rule := range rules {
-		for i, item := range rule.Antecedent {
-			if i != 0 {
-				fmt.Fprintf(w, " ")
-			}
-			fmt.Fprint(w, ctx.itemizer.ToStr(item))
+		if cancelled(c) {
+			return c.Err()
 		}
+		writeItemset(w, &ctx.itemizer, rule.Antecedent)
 		fmt.Fprint(w, " => ")
-		for i, item := range rule.Consequent {
-			if i != 0 {
-				fmt.Fprintf(w, " ")
-			}
-			fmt.Fprint(w, ctx.itemizer.ToStr(item))
-		}
+		writeItemset(w, &ctx.itemizer, rule.Consequent)
 		fmt.Fprintf(
 			w,
 			",%f,%f,%f\n",
@@ -89,117 +135,563 @@ func (ctx Context) WriteRules(
 			rule.Support,
 		)
 	}
-	w.Flush()
-	return nil
+	return w.Flush()
 }
 
-func countItems(path string) (*Itemizer, *itemCount, int, error) {
-	file, err := os.Open(path)
+func countItems(
+	path string,
+	weighted bool,
+) (*Itemizer, *itemCount, int, error) {
+	itemizer := newItemizer()
+	frequency := makeCounts()
+	numTransactions, err := countItemsWith(
+		context.Background(),
+		path,
+		weighted,
+		1,
+		nil,
+		&itemizer,
+		&frequency,
+		new([]int),
+		nil,
+	)
 	if err != nil {
 		return nil, nil, 0, err
 	}
-	defer file.Close()
-
-	frequency := makeCounts()
-	itemizer := newItemizer()
+	return &itemizer, &frequency, numTransactions, nil
+}
 
-	scanner := bufio.NewScanner(file)
+// countItemsWith adds the item frequencies of the dataset at path to
+// frequency, and the number of transactions of each length to lengths, and
+// returns its number of transactions. Uses an existing
+// itemizer, so that several datasets can share item representations. If
+// observe is non-nil, it's called with each transaction's items in increasing
+// order, in the order they occur in the file. Otherwise chunks of the file are
+// read in parallel by up to parallelism goroutines. The bytes read are added
+// to progress. Stops and returns c.Err() if c is cancelled.
+func countItemsWith(
+	c context.Context,
+	path string,
+	weighted bool,
+	parallelism int,
+	progress *progressTracker,
+	itemizer *Itemizer,
+	frequency *itemCount,
+	lengths *[]int,
+	observe func(transaction []Item, count int),
+) (int, error) {
+	if observe == nil {
+		return countChunks(c, path, weighted, parallelism, progress, itemizer, frequency, lengths)
+	}
+	// Transactions must be observed in order, so read the file as one chunk.
+	chunks, err := splitFile(path, 1)
+	if err != nil {
+		return 0, err
+	}
 	numTransactions := 0
-	for scanner.Scan() {
-		numTransactions++
-		itemizer.forEachItem(
-			strings.Split(scanner.Text(), ","),
-			func(item Item) {
-				frequency.increment(item, 1)
-			})
+	err = forEachChunk(path, weighted, chunks, progress, func(_ int, tokens []string, count int) error {
+		if cancelled(c) {
+			return c.Err()
+		}
+		numTransactions += count
+		transaction := make([]Item, 0, len(tokens))
+		itemizer.forEachItem(tokens, func(item Item) {
+			frequency.increment(item, count)
+			transaction = append(transaction, item)
+		})
+		addLength(lengths, len(transaction), count)
+		sort.Slice(transaction, func(i, j int) bool {
+			return transaction[i] < transaction[j]
+		})
+		observe(dedupeSorted(transaction), count)
+		return nil
+	})
+	if c.Err() != nil {
+		return 0, c.Err()
 	}
-	if scanner.Err() != nil {
-		return nil, nil, 0, scanner.Err()
+	if err != nil {
+		return 0, err
 	}
-	return &itemizer, &frequency, numTransactions, nil
+	return numTransactions, nil
 }
 
 type GeneratedItemsets []ItemsetWithCount
 
 // GenerateItemsets generates frequent itemsets with support above minSupport.
+// If the Context was created with WithIncrementalMining(), the itemsets are
+// mined from the retained FP-tree without reading the input again. Dense
+// datasets, where the items occur in a large proportion of transactions, are
+// mined with the Bitset miner instead of FP-growth; see
+// WithDensityThreshold(). Checkpointed Contexts always use FP-growth; see
+// WithCheckpoint().
 func (ctx Context) GenerateItemsets(
 	minSupport float64,
 ) (GeneratedItemsets, error) {
-	return generateFrequentItemsets(
-		ctx.inputCsvPath,
-		minSupport,
-		&ctx.itemizer,
-		&ctx.frequency,
-		ctx.numTransactions,
+	return ctx.GenerateItemsetsContext(context.Background(), minSupport)
+}
+
+// GenerateItemsetsContext is like GenerateItemsets, but stops and returns
+// c.Err() soon after c is cancelled.
+func (ctx Context) GenerateItemsetsContext(
+	c context.Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	if !ctx.options.weighted && len(ctx.options.checkpointDir) == 0 {
+		density := ctx.density()
+		dense := density >= ctx.options.denseThreshold()
+		ctx.options.log().Debug(
+			"selected miner",
+			"dense", dense,
+			"density", density,
+			"density_threshold", ctx.options.denseThreshold(),
+		)
+		if dense {
+			return Bitset{}.MineContext(c, ctx, minSupport)
+		}
+	}
+	return ctx.fpGrowth(c, minSupport)
+}
+
+func (ctx *Context) fpGrowth(
+	c context.Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	itemsets := make(GeneratedItemsets, 0)
+	err := ctx.mineFrequentItemsets(c, minSupport, func(x []ItemsetWithCount) error {
+		itemsets = append(itemsets, x...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return itemsets, nil
+}
+
+// mineFrequentItemsets generates the itemsets with support above minSupport
+// via FP-growth, passing the itemsets generated from each frequent item to
+// emit in turn, as mineTree does. If the Context is checkpointed, the
+// itemsets of items already mined are read from the checkpoint and passed to
+// emit first.
+func (ctx *Context) mineFrequentItemsets(
+	c context.Context,
+	minSupport float64,
+	emit func(itemsets []ItemsetWithCount) error,
+) error {
+	minCount := minCountFor(minSupport, ctx.numTransactions)
+	var checkpoint *miningCheckpoint
+	if len(ctx.options.checkpointDir) > 0 {
+		var err error
+		checkpoint, err = openMiningCheckpoint(ctx.options.checkpointDir, minCount)
+		if err != nil {
+			return err
+		}
+		defer checkpoint.close()
+		if err := checkpoint.replay(emit); err != nil {
+			return err
+		}
+		if len(checkpoint.entries) > 0 {
+			ctx.options.log().Info(
+				"resuming from checkpoint",
+				"dir", ctx.options.checkpointDir,
+				"items_mined", len(checkpoint.entries),
+				"complete", checkpoint.complete,
+			)
+		}
+		if checkpoint.complete {
+			return nil
+		}
+	}
+	logger := ctx.options.log()
+	tree := ctx.tree
+	if tree == nil {
+		start := time.Now()
+		var err error
+		tree, err = buildFrequentTree(
+			c,
+			ctx.inputCsvPaths,
+			ctx.options.weighted,
+			ctx.options.workers(),
+			ctx.options.progress,
+			minCount,
+			&ctx.itemizer,
+			&ctx.frequency,
+		)
+		if err != nil {
+			return err
+		}
+		logger.Debug(
+			"built FP-tree",
+			"phase", PhaseBuildingTree,
+			"nodes", tree.numNodes(),
+			"min_count", minCount,
+			"duration", time.Since(start),
+		)
+	}
+	start := time.Now()
+	numItemsets := 0
+	defer func() {
+		logger.Debug(
+			"mined itemsets",
+			"phase", PhaseMining,
+			"itemsets", numItemsets,
+			"min_support", minSupport,
+			"duration", time.Since(start),
+		)
+	}()
+	if checkpoint == nil {
+		return mineTree(c, tree, minCount, ctx.options.progress, nil,
+			func(_ Item, itemsets []ItemsetWithCount) error {
+				numItemsets += len(itemsets)
+				return emit(itemsets)
+			})
+	}
+	err := mineTree(
+		c,
+		tree,
+		minCount,
+		ctx.options.progress,
+		func(item Item) bool { return checkpoint.done[item] },
+		func(item Item, itemsets []ItemsetWithCount) error {
+			if err := checkpoint.record(item, itemsets); err != nil {
+				return err
+			}
+			numItemsets += len(itemsets)
+			return emit(itemsets)
+		},
 	)
+	if err != nil {
+		return err
+	}
+	return checkpoint.finish()
+}
+
+// density returns the density of the dataset; the average number of items per
+// transaction divided by the number of distinct items. This is the average
+// support of the items.
+func (ctx *Context) density() float64 {
+	numItems := ctx.NumItems()
+	if numItems == 0 || ctx.numTransactions == 0 {
+		return 0
+	}
+	occurrences := 0
+	for length, count := range ctx.lengths {
+		occurrences += length * count
+	}
+	if len(ctx.lengths) == 0 {
+		// A StreamMiner's snapshot doesn't store transaction lengths, but
+		// its items' counts sum to the same.
+		for _, count := range ctx.frequency.counts {
+			occurrences += count
+		}
+	}
+	return float64(occurrences) /
+		float64(ctx.numTransactions) /
+		float64(numItems)
 }
 
+// frequentDensity returns the density of the dataset restricted to items
+// occurring in at least minCount transactions; the average number of such
+// items per transaction divided by the number of such items. This is the
+// average support of those items.
+func (ctx *Context) frequentDensity(minCount int) float64 {
+	items := frequentItems(ctx, minCount)
+	if len(items) == 0 || ctx.numTransactions == 0 {
+		return 0
+	}
+	occurrences := 0
+	for _, item := range items {
+		occurrences += ctx.frequency.get(item)
+	}
+	return float64(occurrences) /
+		float64(ctx.numTransactions) /
+		float64(len(items))
+}
+
+func minCountFor(minSupport float64, numTransactions int) int {
+	return max(1, int(math.Ceil(minSupport*float64(numTransactions))))
+}
+
+// generateFrequentItemsets builds an FP-tree of the frequent items of the
+// transactions in inputCsvPaths, and mines it.
 func generateFrequentItemsets(
-	inputCsvPath string,
+	c context.Context,
+	inputCsvPaths []string,
+	weighted bool,
+	parallelism int,
+	reporter ProgressReporter,
 	minSupport float64,
 	itemizer *Itemizer,
 	frequency *itemCount,
 	numTransactions int,
 ) ([]ItemsetWithCount, error) {
-	file, err := os.Open(inputCsvPath)
+	minCount := minCountFor(minSupport, numTransactions)
+	tree, err := buildFrequentTree(
+		c,
+		inputCsvPaths,
+		weighted,
+		parallelism,
+		reporter,
+		minCount,
+		itemizer,
+		frequency,
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return mineTreeItemsets(c, tree, minCount, reporter)
+}
 
-	minCount := max(1, int(math.Ceil(minSupport*float64(numTransactions))))
+// buildFrequentTree builds an FP-tree of the items of the transactions in
+// inputCsvPaths which occur at least minCount times. Chunks of each file are
+// read in parallel by up to parallelism goroutines, each building its own
+// tree, and the trees are then merged. Stops if c is cancelled, and reports
+// progress to reporter if it's non-nil.
+func buildFrequentTree(
+	c context.Context,
+	inputCsvPaths []string,
+	weighted bool,
+	parallelism int,
+	reporter ProgressReporter,
+	minCount int,
+	itemizer *Itemizer,
+	frequency *itemCount,
+) (*fpTree, error) {
 
-	scanner := bufio.NewScanner(file)
+	progress := startProgress(
+		reporter,
+		PhaseBuildingTree,
+		fileSizes(inputCsvPaths...),
+	)
+	defer progress.finish()
 	tree := newTree()
-	for scanner.Scan() {
-		transaction := itemizer.filter(
-			strings.Split(scanner.Text(), ","),
-			func(i Item) bool {
-				return frequency.get(i) >= minCount
-			})
+	for _, path := range inputCsvPaths {
+		chunks, err := splitFile(path, parallelism)
+		if err != nil {
+			return nil, err
+		}
+		trees := make([]*fpTree, len(chunks))
+		trees[0] = tree
+		for i := 1; i < len(trees); i++ {
+			trees[i] = newTree()
+		}
+		insert := func(i int, tokens []string, count int) error {
+			if cancelled(c) {
+				return c.Err()
+			}
+			// The itemizer is shared between goroutines, so must only be
+			// read here.
+			transaction := itemizer.lookup(tokens)
+			frequent := transaction[:0]
+			for _, item := range transaction {
+				if frequency.get(item) >= minCount {
+					frequent = append(frequent, item)
+				}
+			}
+			transaction = frequent
 
-		if len(transaction) == 0 {
-			continue
+			if len(transaction) == 0 {
+				return nil
+			}
+			// Sort by decreasing frequency, tie break lexicographically.
+			sort.SliceStable(transaction, func(i, j int) bool {
+				a := transaction[i]
+				b := transaction[j]
+				if frequency.get(a) == frequency.get(b) {
+					return itemizer.cmp(a, b)
+				}
+				return frequency.get(a) > frequency.get(b)
+			})
+			trees[i].Insert(transaction, count)
+			return nil
 		}
-		// Sort by decreasing frequency, tie break lexicographically.
-		sort.SliceStable(transaction, func(i, j int) bool {
-			a := transaction[i]
-			b := transaction[j]
-			if frequency.get(a) == frequency.get(b) {
-				return itemizer.cmp(a, b)
+		if err := forEachChunk(path, weighted, chunks, progress, insert); err != nil {
+			if c.Err() != nil {
+				return nil, c.Err()
 			}
-			return frequency.get(a) > frequency.get(b)
-		})
-		tree.Insert(transaction, 1)
-	}
-	if scanner.Err() != nil {
-		return nil, scanner.Err()
+			return nil, err
+		}
+		for _, t := range trees[1:] {
+			t.forEachTransaction(tree.Insert)
+		}
 	}
-
-	return fpGrowth(tree, make([]Item, 0), minCount), nil
+	progress.setTreeNodes(tree.numNodes())
+	return tree, nil
 }
 
 // Context stores context for an analysis of itemset transactions.
 type Context struct {
-	inputCsvPath    string
+	// inputCsvPaths stores the initial dataset, followed by any batches
+	// added by AddTransactions().
+	inputCsvPaths   []string
 	itemizer        Itemizer
 	frequency       itemCount
 	numTransactions int
+	// lengths stores the number of transactions of each length.
+	lengths []int
+	options options
+	// tree retains every transaction when mining incrementally.
+	tree *fpTree
+	// sample is a uniform random sample of the transactions, if requested.
+	sample *reservoir
+}
+
+// NumTransactions returns the number of transactions in the Context's
+// dataset.
+func (ctx Context) NumTransactions() int {
+	return ctx.numTransactions
+}
+
+// NumItems returns the number of distinct items in the Context's dataset.
+func (ctx Context) NumItems() int {
+	return len(ctx.itemizer.strToItem)
 }
 
 // Init creates a Context. Performs a first pass on dataset, counting
-// item frequencies and number of transactions.
-func Init(inputCsvPath string) (Context, error) {
-	itemizer, frequency, numTransactions, err := countItems(inputCsvPath)
-	if err != nil {
+// item frequencies and number of transactions. Options can be passed to
+// change how the dataset is interpreted, for example
+// WithWeightedTransactions().
+func Init(inputCsvPath string, opts ...Option) (Context, error) {
+	return InitContext(context.Background(), inputCsvPath, opts...)
+}
+
+// InitContext is like Init, but stops and returns c.Err() soon after c is
+// cancelled.
+func InitContext(c context.Context, inputCsvPath string, opts ...Option) (Context, error) {
+	o := makeOptions(opts)
+	if len(o.checkpointDir) > 0 && (o.incremental || o.sampleSize > 0) {
+		return Context{}, errors.New("checkpoints can't be used with incremental mining or sampling")
+	}
+	ctx := newContext(o)
+	if err := ctx.AddTransactionsContext(c, inputCsvPath); err != nil {
 		return Context{}, err
 	}
-	return Context{
-		inputCsvPath:    inputCsvPath,
-		itemizer:        *itemizer,
-		frequency:       *frequency,
-		numTransactions: numTransactions,
-	}, nil
+	return ctx, nil
+}
+
+func newContext(o options) Context {
+	ctx := Context{
+		itemizer:  newItemizer(),
+		frequency: makeCounts(),
+		options:   o,
+	}
+	if o.incremental {
+		ctx.tree = newTree()
+	}
+	if o.sampleSize > 0 {
+		ctx.sample = newReservoir(o.sampleSize, o.sampleSeed)
+	}
+	return ctx
+}
+
+// AddTransactions adds a batch of transactions from the CSV file at path to
+// the Context, updating the item frequencies and number of transactions.
+// Itemsets and rules generated afterwards include the new transactions. This
+// is cheapest if the Context was created with WithIncrementalMining(), as
+// then neither the existing dataset nor the batch needs to be read again.
+// If an error is returned, the Context has been partially updated and should
+// be discarded.
+func (ctx *Context) AddTransactions(path string) error {
+	return ctx.AddTransactionsContext(context.Background(), path)
+}
+
+// AddTransactionsContext is like AddTransactions, but stops and returns
+// c.Err() soon after c is cancelled.
+func (ctx *Context) AddTransactionsContext(c context.Context, path string) error {
+	var observe func([]Item, int)
+	if ctx.tree != nil || ctx.sample != nil {
+		observe = ctx.observe
+	}
+	start := time.Now()
+	progress := startProgress(ctx.options.progress, PhaseCounting, fileSizes(path))
+	defer progress.finish()
+	numTransactions, err := countItemsWith(
+		c,
+		path,
+		ctx.options.weighted,
+		ctx.options.workers(),
+		progress,
+		&ctx.itemizer,
+		&ctx.frequency,
+		&ctx.lengths,
+		observe,
+	)
+	if err != nil {
+		return err
+	}
+	ctx.inputCsvPaths = append(ctx.inputCsvPaths, path)
+	ctx.numTransactions += numTransactions
+	ctx.options.log().Debug(
+		"counted items",
+		"phase", PhaseCounting,
+		"path", path,
+		"transactions", numTransactions,
+		"items", ctx.itemizer.numItems,
+		"duration", time.Since(start),
+	)
+	if len(ctx.options.checkpointDir) > 0 {
+		return ctx.saveCheckpoint()
+	}
+	return nil
+}
+
+// observe records a transaction in the retained tree and sample, if any.
+func (ctx *Context) observe(transaction []Item, count int) {
+	if ctx.tree != nil {
+		ctx.tree.Insert(transaction, count)
+	}
+	if ctx.sample != nil {
+		ctx.sample.add(transaction, count)
+	}
+}
+
+// forEachItemset calls fn for every transaction in the Context's dataset,
+// with its items in increasing order. Reads from the retained tree if
+// there is one, rather than the input files. Stops and returns c.Err() if c
+// is cancelled.
+func (ctx *Context) forEachItemset(
+	c context.Context,
+	fn func(transaction []Item, count int),
+) error {
+	if ctx.tree != nil {
+		ctx.tree.forEachTransaction(func(transaction []Item, count int) {
+			if !cancelled(c) {
+				fn(transaction, count)
+			}
+		})
+		return c.Err()
+	}
+	return ctx.forEachTransaction(c, func(tokens []string, count int) error {
+		transaction := ctx.itemizer.lookup(tokens)
+		sort.Slice(transaction, func(i, j int) bool {
+			return transaction[i] < transaction[j]
+		})
+		fn(dedupeSorted(transaction), count)
+		return nil
+	})
+}
+
+// forEachTransaction calls fn for every transaction in the Context's
+// dataset, including added batches. Stops and returns c.Err() if c is
+// cancelled.
+func (ctx *Context) forEachTransaction(
+	c context.Context,
+	fn func(tokens []string, count int) error,
+) error {
+	for _, path := range ctx.inputCsvPaths {
+		err := forEachTransaction(path, ctx.options.weighted, func(tokens []string, count int) error {
+			if cancelled(c) {
+				return c.Err()
+			}
+			return fn(tokens, count)
+		})
+		if c.Err() != nil {
+			return c.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func flatten(rules2d [][]Rule) []Rule {
@@ -207,7 +699,7 @@ func flatten(rules2d [][]Rule) []Rule {
 	for _, r := range rules2d {
 		n += len(r)
 	}
-	rules := make([]Rule, n)
+	rules := make([]Rule, 0, n)
 	for _, r := range rules2d {
 		rules = append(rules, r...)
 	}
@@ -221,14 +713,50 @@ func (ctx Context) GenerateRules(
 	minConfidence float64,
 	minLift float64,
 ) []Rule {
+	rules, _ := ctx.GenerateRulesContext(
+		context.Background(),
+		itemsets,
+		minConfidence,
+		minLift,
+	)
+	return rules
+}
+
+// GenerateRulesContext is like GenerateRules, but stops and returns c.Err()
+// soon after c is cancelled.
+func (ctx Context) GenerateRulesContext(
+	c context.Context,
+	itemsets GeneratedItemsets,
+	minConfidence float64,
+	minLift float64,
+) ([]Rule, error) {
+	start := time.Now()
+	progress := startProgress(ctx.options.progress, PhaseRules, int64(len(itemsets)))
+	defer progress.finish()
 	// To avoid expensive resizes when generating an unknown number of rules,
 	// generateRules outputs a slice of slices. So merge them together into a
 	// single slice to make things cleaner.
-	rules2d := generateRules(
+	rules2d, err := generateRulesContext(
+		c,
 		itemsets,
 		ctx.numTransactions,
 		minConfidence,
 		minLift,
+		ctx.options.workers(),
+		progress,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rules := flatten(rules2d)
+	ctx.options.log().Debug(
+		"generated rules",
+		"phase", PhaseRules,
+		"itemsets", len(itemsets),
+		"rules", len(rules),
+		"min_confidence", minConfidence,
+		"min_lift", minLift,
+		"duration", time.Since(start),
 	)
-	return flatten(rules2d)
+	return rules, nil
 }
@@ -0,0 +1,146 @@
+package fpgrowth
+
+import (
+	"context"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// randomTransactions generates n transactions over a small alphabet, with
+// some items much more frequent than others.
+func randomTransactions(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	items := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	lines := make([]string, n)
+	for i := range lines {
+		transaction := make([]string, 0)
+		for j, item := range items {
+			if rng.Intn(len(items)+2) > j {
+				transaction = append(transaction, item)
+			}
+		}
+		lines[i] = strings.Join(transaction, ",")
+	}
+	return lines
+}
+
+func TestIncrementalMining(t *testing.T) {
+	lines := randomTransactions(600, 1)
+	full := writeTestCsv(t, lines...)
+	batches := []string{
+		writeTestCsv(t, lines[:200]...),
+		writeTestCsv(t, lines[200:450]...),
+		writeTestCsv(t, lines[450:]...),
+	}
+
+	fullCtx, err := Init(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, incremental := range []bool{false, true} {
+		var opts []Option
+		if incremental {
+			opts = append(opts, WithIncrementalMining())
+		}
+		ctx, err := Init(batches[0], opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, batch := range batches[1:] {
+			if err := ctx.AddTransactions(batch); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if ctx.numTransactions != fullCtx.numTransactions {
+			t.Error("Expected ", fullCtx.numTransactions, " transactions, got ", ctx.numTransactions)
+		}
+		for _, minSupport := range []float64{0.05, 0.2, 0.5} {
+			expected, err := fullCtx.GenerateItemsets(minSupport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			observed, err := ctx.GenerateItemsets(minSupport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := itemsetCounts(fullCtx, expected)
+			o := itemsetCounts(ctx, observed)
+			if !equalCounts(e, o) {
+				t.Error("incremental=", incremental, " minSupport=", minSupport,
+					": itemsets ", o, " don't match full remine ", e)
+			}
+			expectedRules := fullCtx.GenerateRules(expected, 0.1, 1)
+			observedRules := ctx.GenerateRules(observed, 0.1, 1)
+			if len(expectedRules) != len(observedRules) {
+				t.Error("incremental=", incremental, " minSupport=", minSupport,
+					": generated ", len(observedRules), " rules, expected ", len(expectedRules))
+			}
+		}
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	lines := randomTransactions(600, 9)
+	plain, err := Init(writeTestCsv(t, lines...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	incremental, err := Init(writeTestCsv(t, lines...), WithIncrementalMining())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense, err := Init(writeTestCsv(t, denseTransactions(300, 9)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, ctx := range []Context{plain, incremental, dense} {
+		if _, err := ctx.GenerateItemsetsContext(c, 0.01); err != context.Canceled {
+			t.Error("Expected GenerateItemsetsContext to be cancelled, got ", err)
+		}
+		for _, name := range []string{"fpgrowth", "apriori", "eclat", "declat", "bitset"} {
+			miner, _ := MinerByName(name)
+			if _, err := miner.MineContext(c, ctx, 0.01); err != context.Canceled {
+				t.Error("Expected ", name, " to be cancelled, got ", err)
+			}
+		}
+		itemsets, err := ctx.GenerateItemsetsContext(context.Background(), 0.01)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ctx.GenerateRulesContext(c, itemsets, 0, 0); err != context.Canceled {
+			t.Error("Expected GenerateRulesContext to be cancelled, got ", err)
+		}
+		rules, err := ctx.GenerateRulesContext(context.Background(), itemsets, 0, 0)
+		if err != nil || !reflect.DeepEqual(rules, ctx.GenerateRules(itemsets, 0, 0)) {
+			t.Error("Expected GenerateRulesContext to match GenerateRules, got ", err)
+		}
+		if _, err := ctx.GenerateNegativeRulesContext(c, itemsets, 0, 1); err != context.Canceled {
+			t.Error("Expected GenerateNegativeRulesContext to be cancelled, got ", err)
+		}
+		output := filepath.Join(t.TempDir(), "output.csv")
+		if err := ctx.WriteItemsetsContext(c, itemsets, output); err != context.Canceled {
+			t.Error("Expected WriteItemsetsContext to be cancelled, got ", err)
+		}
+		if err := ctx.WriteRulesContext(c, output, rules); err != context.Canceled {
+			t.Error("Expected WriteRulesContext to be cancelled, got ", err)
+		}
+	}
+	for _, opts := range [][]Option{nil, {WithIncrementalMining()}} {
+		if _, err := InitContext(c, writeTestCsv(t, lines...), opts...); err != context.Canceled {
+			t.Error("Expected InitContext to be cancelled, got ", err)
+		}
+	}
+	sampled, err := Init(writeTestCsv(t, lines...), WithSample(100, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sampled.GenerateItemsetsFromSampleContext(c, 0.01, 0.008, 10); err != context.Canceled {
+		t.Error("Expected GenerateItemsetsFromSampleContext to be cancelled, got ", err)
+	}
+}
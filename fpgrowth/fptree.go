@@ -1,62 +1,149 @@
 package fpgrowth
 
-type itemToNodeSlice map[Item][]*fpNode
+import (
+	"context"
+	"slices"
+	"sync"
+)
 
+// fpNode is a node of an fpTree. Nodes refer to each other by their index in
+// the tree's nodes slice, rather than by pointer, so that a tree is a few
+// contiguous allocations which can be reused, and which the garbage collector
+// doesn't need to scan.
 type fpNode struct {
-	item     Item
-	count    int
-	parent   *fpNode
-	children []*fpNode
-	depth    int
+	item  Item
+	count int
+	// parent, firstChild, nextSibling and nextSameItem are indices into the
+	// tree's nodes, or rootNode if there's no such node. Children of a node
+	// are linked through nextSibling, and the nodes with the same item are
+	// linked through nextSameItem, starting from the tree's heads.
+	parent       int32
+	firstChild   int32
+	nextSibling  int32
+	nextSameItem int32
 }
 
 type fpTree struct {
-	root     *fpNode
-	itemList itemToNodeSlice
-	counts   itemCount
+	// nodes is the arena in which the tree's nodes are stored. The root is
+	// always nodes[rootNode].
+	nodes []fpNode
+	// heads maps an item to the first node in its list of nodes.
+	heads []int32
+	// free lists the indices of nodes deleted by Remove, for reuse.
+	free   []int32
+	counts itemCount
 }
 
 const invalidItem = Item(0)
 
-func newNode(item Item, parent *fpNode, depth int) *fpNode {
-	return &fpNode{
-		item:     item,
-		count:    0,
-		parent:   parent,
-		children: make([]*fpNode, 0),
-		depth:    depth,
-	}
-}
+// rootNode is the index of the root. As the root is never a child, sibling,
+// or in an item's node list, it's also used as the null index.
+const rootNode = int32(0)
 
 func newTree() *fpTree {
 	return &fpTree{
-		root:     newNode(invalidItem, nil, 0),
-		itemList: make(itemToNodeSlice),
-		counts:   makeCounts(),
+		nodes:  []fpNode{{item: invalidItem}},
+		heads:  make([]int32, 0),
+		free:   make([]int32, 0),
+		counts: makeCounts(),
+	}
+}
+
+// numNodes returns the number of nodes in the tree, excluding the root.
+func (tree *fpTree) numNodes() int {
+	return len(tree.nodes) - len(tree.free) - 1
+}
+
+// conditionalBase holds the state used by fpGrowth to build a conditional
+// tree. These are pooled, so that their arenas don't need to be allocated
+// again for each item.
+type conditionalBase struct {
+	tree *fpTree
+	// counts is the count of each item in the conditional pattern base, and
+	// items lists the items with non-zero counts.
+	counts      []int
+	items       []Item
+	transaction []Item
+}
+
+var conditionalBasePool = sync.Pool{
+	New: func() any {
+		return &conditionalBase{tree: newTree()}
+	},
+}
+
+// reset removes every node from the tree, retaining its allocations.
+func (tree *fpTree) reset() {
+	tree.nodes = append(tree.nodes[:0], fpNode{item: invalidItem})
+	tree.heads = tree.heads[:0]
+	tree.free = tree.free[:0]
+	tree.counts.counts = tree.counts.counts[:0]
+}
+
+// head returns the first node in item's list of nodes, or rootNode if there
+// are no nodes for item.
+func (tree *fpTree) head(item Item) int32 {
+	if int(item) >= len(tree.heads) {
+		return rootNode
 	}
+	return tree.heads[item]
+}
+
+func (tree *fpTree) newNode(item Item, parent int32) int32 {
+	node := fpNode{
+		item:         item,
+		parent:       parent,
+		nextSibling:  tree.nodes[parent].firstChild,
+		nextSameItem: tree.head(item),
+	}
+	var idx int32
+	if n := len(tree.free); n > 0 {
+		idx = tree.free[n-1]
+		tree.free = tree.free[:n-1]
+		tree.nodes[idx] = node
+	} else {
+		idx = int32(len(tree.nodes))
+		tree.nodes = append(tree.nodes, node)
+	}
+	tree.nodes[parent].firstChild = idx
+	for int(item) >= len(tree.heads) {
+		tree.heads = append(tree.heads, rootNode)
+	}
+	tree.heads[item] = idx
+	return idx
+}
+
+// child returns the child of parent with item, or rootNode if there is none.
+// The child found is moved to the front of parent's children, so children of
+// frequent items, which are looked up most, are found soonest.
+func (tree *fpTree) child(parent int32, item Item) int32 {
+	prev := rootNode
+	for idx := tree.nodes[parent].firstChild; idx != rootNode; idx = tree.nodes[idx].nextSibling {
+		if tree.nodes[idx].item != item {
+			prev = idx
+			continue
+		}
+		if prev != rootNode {
+			tree.nodes[prev].nextSibling = tree.nodes[idx].nextSibling
+			tree.nodes[idx].nextSibling = tree.nodes[parent].firstChild
+			tree.nodes[parent].firstChild = idx
+		}
+		return idx
+	}
+	return rootNode
 }
 
 func (tree *fpTree) Insert(transaction []Item, count int) {
-	tree.root.count += count
-	parent := tree.root
-	depth := 1
+	tree.nodes[rootNode].count += count
+	parent := rootNode
 	for _, item := range transaction {
-		var node *fpNode
-		for idx := range parent.children {
-			if parent.children[idx].item == item {
-				node = parent.children[idx]
-				break
-			}
-		}
-		if node == nil {
-			node = newNode(item, parent, depth)
-			parent.children = append(parent.children, node)
-			tree.itemList[item] = append(tree.itemList[item], node)
+		node := tree.child(parent, item)
+		if node == rootNode {
+			node = tree.newNode(item, parent)
 		}
 		tree.counts.increment(item, count)
-		node.count += count
+		tree.nodes[node].count += count
 		parent = node
-		depth++
 	}
 }
 
@@ -73,20 +160,15 @@ func reverse(a []Item) {
 	}
 }
 
-func isRoot(node *fpNode) bool {
-	return node == nil || node.item == invalidItem
-}
-
-func pathFromRootToExcluding(node *fpNode) []Item {
-	path := make([]Item, 0, node.depth-1)
-	for {
-		node = node.parent
-		if isRoot(node) {
-			reverse(path)
-			return path
-		}
-		path = append(path, node.item)
+// appendPathTo appends the items on the path from the root to node, excluding
+// node, to path.
+func (tree *fpTree) appendPathTo(path []Item, node int32) []Item {
+	start := len(path)
+	for idx := tree.nodes[node].parent; idx != rootNode; idx = tree.nodes[idx].parent {
+		path = append(path, tree.nodes[idx].item)
 	}
+	reverse(path[start:])
+	return path
 }
 
 func appendSorted(itemset []Item, item Item) []Item {
@@ -108,24 +190,271 @@ func appendSorted(itemset []Item, item Item) []Item {
 	return xs
 }
 
+// count sets base.counts to the counts of the items in the prefix paths of
+// item's nodes in tree.
+func (base *conditionalBase) count(tree *fpTree, item Item) {
+	for _, i := range base.items {
+		base.counts[i] = 0
+	}
+	base.items = base.items[:0]
+	for len(base.counts) < len(tree.heads) {
+		base.counts = append(base.counts, 0)
+	}
+	for idx := tree.head(item); idx != rootNode; idx = tree.nodes[idx].nextSameItem {
+		count := tree.nodes[idx].count
+		for p := tree.nodes[idx].parent; p != rootNode; p = tree.nodes[p].parent {
+			i := tree.nodes[p].item
+			if base.counts[i] == 0 {
+				base.items = append(base.items, i)
+			}
+			base.counts[i] += count
+		}
+	}
+}
+
+// build resets base.tree to the conditional tree of item's prefix paths in
+// tree. Items occurring fewer than minCount times in the prefix paths are
+// pruned, and the remaining items of each path are ordered by decreasing
+// count in the conditional base, so that the tree is as compact as possible.
+func (base *conditionalBase) build(tree *fpTree, item Item, minCount int) {
+	base.count(tree, item)
+	base.tree.reset()
+	for idx := tree.head(item); idx != rootNode; idx = tree.nodes[idx].nextSameItem {
+		base.transaction = base.transaction[:0]
+		for p := tree.nodes[idx].parent; p != rootNode; p = tree.nodes[p].parent {
+			if i := tree.nodes[p].item; base.counts[i] >= minCount {
+				base.transaction = append(base.transaction, i)
+			}
+		}
+		slices.SortFunc(base.transaction, func(a, b Item) int {
+			if base.counts[a] != base.counts[b] {
+				return base.counts[b] - base.counts[a]
+			}
+			return int(a) - int(b)
+		})
+		base.tree.Insert(base.transaction, tree.nodes[idx].count)
+	}
+}
+
+// singlePath returns the nodes of tree, in order from the root, if it consists
+// of a single path, otherwise it returns nil.
+func (tree *fpTree) singlePath() []int32 {
+	path := make([]int32, 0)
+	for idx := tree.nodes[rootNode].firstChild; idx != rootNode; idx = tree.nodes[idx].firstChild {
+		if tree.nodes[idx].nextSibling != rootNode {
+			return nil
+		}
+		path = append(path, idx)
+	}
+	return path
+}
+
+// singlePathItemsets returns every combination of the items on path with
+// count at least minCount, added to itemset. The count of a combination is the
+// count of its deepest node, as counts decrease along the path.
+func (tree *fpTree) singlePathItemsets(path []int32, itemset []Item, minCount int) []ItemsetWithCount {
+	itemsets := make([]ItemsetWithCount, 0)
+	for _, idx := range path {
+		node := tree.nodes[idx]
+		if node.count < minCount {
+			break
+		}
+		// Every combination ending at node is node's item added to itemset,
+		// or to a combination ending before it.
+		n := len(itemsets)
+		itemsets = append(itemsets, ItemsetWithCount{
+			Itemset: appendSorted(itemset, node.item),
+			Count:   node.count,
+		})
+		for _, iwc := range itemsets[:n] {
+			itemsets = append(itemsets, ItemsetWithCount{
+				Itemset: appendSorted(iwc.Itemset, node.item),
+				Count:   node.count,
+			})
+		}
+	}
+	return itemsets
+}
+
 func fpGrowth(tree *fpTree, itemset []Item, minCount int) []ItemsetWithCount {
+	itemsets, _ := fpGrowthContext(context.Background(), tree, itemset, minCount, nil, nil)
+	return itemsets
+}
+
+// mineTree generates the itemsets of tree with count at least minCount, and
+// calls emit with the itemsets generated from each of tree's items in turn.
+// emit must not retain the slice it's passed. Items for which skip returns
+// true aren't mined; skip may be nil. Reports progress to reporter if it's
+// non-nil. Stops and returns c.Err() if c is cancelled.
+func mineTree(
+	c context.Context,
+	tree *fpTree,
+	minCount int,
+	reporter ProgressReporter,
+	skip func(item Item) bool,
+	emit func(item Item, itemsets []ItemsetWithCount) error,
+) error {
+	numItems := 0
+	for item, head := range tree.heads {
+		if head != rootNode && tree.counts.get(Item(item)) >= minCount {
+			numItems++
+		}
+	}
+	progress := startProgress(reporter, PhaseMining, int64(numItems))
+	defer progress.finish()
+	_, err := fpGrowthContext(
+		c,
+		tree,
+		make([]Item, 0),
+		minCount,
+		func(item Item) bool {
+			if skip != nil && skip(item) {
+				progress.add(1)
+				return true
+			}
+			return false
+		},
+		func(item Item, itemsets []ItemsetWithCount) error {
+			progress.add(1)
+			progress.addItemsets(len(itemsets))
+			return emit(item, itemsets)
+		},
+	)
+	return err
+}
+
+// mineTreeItemsets is mineTree, returning all of the itemsets.
+func mineTreeItemsets(
+	c context.Context,
+	tree *fpTree,
+	minCount int,
+	reporter ProgressReporter,
+) ([]ItemsetWithCount, error) {
+	itemsets := make([]ItemsetWithCount, 0)
+	err := mineTree(c, tree, minCount, reporter, nil, func(_ Item, x []ItemsetWithCount) error {
+		itemsets = append(itemsets, x...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return itemsets, nil
+}
+
+// fpGrowthContext is fpGrowth, but stops and returns c.Err() if c is
+// cancelled. If emit is non-nil, the itemsets generated from each of tree's
+// items are passed to it rather than returned, skipping items for which skip
+// returns true.
+func fpGrowthContext(
+	c context.Context,
+	tree *fpTree,
+	itemset []Item,
+	minCount int,
+	skip func(item Item) bool,
+	emit func(item Item, itemsets []ItemsetWithCount) error,
+) ([]ItemsetWithCount, error) {
+	// Single paths are enumerated all at once, so only when the itemsets
+	// needn't be passed to emit item by item.
+	if path := tree.singlePath(); path != nil && emit == nil {
+		return tree.singlePathItemsets(path, itemset, minCount), nil
+	}
 	itemsets := make([]ItemsetWithCount, 0)
-	for item, itemList := range tree.itemList {
-		if tree.counts.get(item) < minCount {
+	base := conditionalBasePool.Get().(*conditionalBase)
+	defer conditionalBasePool.Put(base)
+	for item, head := range tree.heads {
+		if head == rootNode || tree.counts.get(Item(item)) < minCount {
 			continue
 		}
-		conditionalTree := newTree()
-		for _, leaf := range itemList {
-			transaction := pathFromRootToExcluding(leaf)
-			conditionalTree.Insert(transaction, leaf.count)
+		if cancelled(c) {
+			return nil, c.Err()
 		}
-		path := appendSorted(itemset, item)
+		if skip != nil && skip(Item(item)) {
+			continue
+		}
+		base.build(tree, Item(item), minCount)
+		path := appendSorted(itemset, Item(item))
 		itemsets = append(itemsets, ItemsetWithCount{
 			Itemset: path,
-			Count:   conditionalTree.root.count,
+			Count:   base.tree.nodes[rootNode].count,
 		})
-		x := fpGrowth(conditionalTree, path, minCount)
+		x, err := fpGrowthContext(c, base.tree, path, minCount, nil, nil)
+		if err != nil {
+			return nil, err
+		}
 		itemsets = append(itemsets, x...)
+		if emit != nil {
+			if err := emit(Item(item), itemsets); err != nil {
+				return nil, err
+			}
+			itemsets = itemsets[:0]
+		}
 	}
-	return itemsets
+	return itemsets, nil
+}
+
+// unlink removes node from the list starting at *head, whose nodes are linked
+// by the field returned by next.
+func (tree *fpTree) unlink(head *int32, node int32, next func(*fpNode) *int32) {
+	for link := head; *link != rootNode; link = next(&tree.nodes[*link]) {
+		if *link == node {
+			*link = *next(&tree.nodes[node])
+			return
+		}
+	}
+}
+
+func nextSibling(node *fpNode) *int32  { return &node.nextSibling }
+func nextSameItem(node *fpNode) *int32 { return &node.nextSameItem }
+
+// Remove reverses an Insert of transaction with count. Nodes whose count
+// drops to zero are removed from the tree, and their space reused.
+func (tree *fpTree) Remove(transaction []Item, count int) {
+	tree.nodes[rootNode].count -= count
+	parent := rootNode
+	for _, item := range transaction {
+		node := tree.child(parent, item)
+		if node == rootNode {
+			panic("Tried to remove transaction that's not in tree!")
+		}
+		tree.counts.increment(item, -count)
+		tree.nodes[node].count -= count
+		if tree.nodes[node].count == 0 {
+			// The node's remaining children are on this transaction's path,
+			// so they'll be removed by the following iterations.
+			tree.unlink(&tree.nodes[parent].firstChild, node, nextSibling)
+			tree.unlink(&tree.heads[item], node, nextSameItem)
+			tree.free = append(tree.free, node)
+		}
+		parent = node
+	}
+}
+
+// clone returns a deep copy of the tree.
+func (tree *fpTree) clone() *fpTree {
+	return &fpTree{
+		nodes:  append([]fpNode(nil), tree.nodes...),
+		heads:  append([]int32(nil), tree.heads...),
+		free:   append([]int32(nil), tree.free...),
+		counts: itemCount{counts: append([]int(nil), tree.counts.counts...)},
+	}
+}
+
+// forEachTransaction calls fn with each distinct transaction inserted into the
+// tree, and the number of times it was inserted. A node's count, less its
+// children's counts, is the number of transactions ending at it.
+func (tree *fpTree) forEachTransaction(fn func(transaction []Item, count int)) {
+	var visit func(node int32, path []Item)
+	visit = func(node int32, path []Item) {
+		count := tree.nodes[node].count
+		for child := tree.nodes[node].firstChild; child != rootNode; child = tree.nodes[child].nextSibling {
+			count -= tree.nodes[child].count
+			visit(child, append(path, tree.nodes[child].item))
+		}
+		if count > 0 && len(path) > 0 {
+			transaction := make([]Item, len(path))
+			copy(transaction, path)
+			fn(transaction, count)
+		}
+	}
+	visit(rootNode, make([]Item, 0))
 }
@@ -0,0 +1,211 @@
+package fpgrowth
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// zipfTransactions generates n transactions over numItems items, with item
+// frequencies following a Zipf distribution, as in market basket data.
+func zipfTransactions(n int, numItems uint64, seed int64) [][]Item {
+	rng := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(rng, 1.2, 1, numItems-1)
+	transactions := make([][]Item, n)
+	for i := range transactions {
+		transaction := make([]Item, 0)
+		for j := 0; j < 2+rng.Intn(12); j++ {
+			transaction = append(transaction, Item(zipf.Uint64()+1))
+		}
+		sort.Slice(transaction, func(a, b int) bool {
+			return transaction[a] < transaction[b]
+		})
+		transactions[i] = dedupeSorted(transaction)
+	}
+	return transactions
+}
+
+func treeTransactions(tree *fpTree) map[string]int {
+	transactions := make(map[string]int)
+	tree.forEachTransaction(func(transaction []Item, count int) {
+		transactions[itemSliceKey(transaction)] += count
+	})
+	return transactions
+}
+
+func TestFPTreeRemove(t *testing.T) {
+	transactions := zipfTransactions(200, 20, 2)
+	tree := newTree()
+	for _, transaction := range transactions {
+		tree.Insert(transaction, 1)
+	}
+	full := tree.clone()
+	expected := treeTransactions(full)
+	for _, transaction := range transactions[:100] {
+		tree.Remove(transaction, 1)
+	}
+	numNodes := len(tree.nodes)
+	for _, transaction := range transactions[:100] {
+		tree.Insert(transaction, 1)
+	}
+	if len(tree.nodes) != numNodes {
+		t.Error("Expected removed nodes to be reused; arena grew from ",
+			numNodes, " to ", len(tree.nodes))
+	}
+	if observed := treeTransactions(tree); !equalCounts(observed, expected) {
+		t.Error("Expected transactions ", expected, " got ", observed)
+	}
+	if observed := treeTransactions(full); !equalCounts(observed, expected) {
+		t.Error("Clone changed by changes to original tree")
+	}
+	for _, transaction := range transactions {
+		tree.Remove(transaction, 1)
+	}
+	if len(treeTransactions(tree)) != 0 || tree.nodes[rootNode].count != 0 {
+		t.Error("Expected empty tree")
+	}
+	for item := range tree.heads {
+		if tree.head(Item(item)) != rootNode {
+			t.Error("Expected no nodes for item ", item)
+		}
+	}
+}
+
+// bruteForceItemsets counts every subset of every transaction, and returns
+// those occurring at least minCount times.
+func bruteForceItemsets(transactions [][]Item, minCount int) map[string]int {
+	counts := make(map[string]int)
+	for _, transaction := range transactions {
+		for mask := 1; mask < 1<<len(transaction); mask++ {
+			subset := make([]Item, 0)
+			for i, item := range transaction {
+				if mask&(1<<i) != 0 {
+					subset = append(subset, item)
+				}
+			}
+			counts[itemSliceKey(subset)]++
+		}
+	}
+	for key, count := range counts {
+		if count < minCount {
+			delete(counts, key)
+		}
+	}
+	return counts
+}
+
+func TestFPGrowthConditionalTrees(t *testing.T) {
+	singlePath := make([][]Item, 0)
+	for i := 1; i <= 8; i++ {
+		for j := 0; j < i; j++ {
+			singlePath = append(singlePath, []Item{1, 2, 3, 4, 5, 6, 7, 8}[:9-i])
+		}
+	}
+	datasets := map[string][][]Item{
+		"zipf":        zipfTransactions(300, 30, 3),
+		"single path": singlePath,
+	}
+	for name, transactions := range datasets {
+		// Insert the transactions unfiltered and in item order, as
+		// incremental mining does.
+		tree := newTree()
+		for _, transaction := range transactions {
+			tree.Insert(transaction, 1)
+		}
+		for _, minCount := range []int{1, 3, 10} {
+			expected := bruteForceItemsets(transactions, minCount)
+			itemsets := fpGrowth(tree, make([]Item, 0), minCount)
+			observed := make(map[string]int)
+			for _, iwc := range itemsets {
+				observed[itemSliceKey(iwc.Itemset)] = iwc.Count
+			}
+			if len(itemsets) != len(observed) || !equalCounts(observed, expected) {
+				t.Error(name, " minCount=", minCount, ": expected ", len(expected),
+					" itemsets, got ", len(itemsets))
+			}
+		}
+	}
+}
+
+func benchmarkFPGrowth(b *testing.B, transactions [][]Item, minSupport float64) {
+	tree := newTree()
+	for _, transaction := range transactions {
+		tree.Insert(transaction, 1)
+	}
+	minCount := minCountFor(minSupport, len(transactions))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fpGrowth(tree, make([]Item, 0), minCount)
+	}
+}
+
+func BenchmarkFPGrowthZipf(b *testing.B) {
+	benchmarkFPGrowth(b, zipfTransactions(20000, 500, 1), 0.002)
+}
+
+func BenchmarkFPGrowthKosarak(b *testing.B) {
+	input := "../datasets/kosarak.csv"
+	if _, err := os.Stat(input); err != nil {
+		b.Skip("Dataset not available: ", err)
+	}
+	itemizer, frequency, numTransactions, err := countItems(input, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := generateFrequentItemsets(
+			context.Background(),
+			[]string{input},
+			false,
+			runtime.GOMAXPROCS(0),
+			nil,
+			0.01,
+			itemizer,
+			frequency,
+			numTransactions,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGenerateItemsetsZipf reads, builds the FP-tree of and mines a
+// generated dataset, so it runs without any of the datasets directory.
+func BenchmarkGenerateItemsetsZipf(b *testing.B) {
+	transactions := zipfTransactions(50000, 1000, 2)
+	var csv strings.Builder
+	for _, transaction := range transactions {
+		for i, item := range transaction {
+			if i > 0 {
+				csv.WriteByte(',')
+			}
+			fmt.Fprintf(&csv, "i%d", item)
+		}
+		csv.WriteByte('\n')
+	}
+	input := filepath.Join(b.TempDir(), "zipf.csv")
+	if err := os.WriteFile(input, []byte(csv.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+	ctx, err := Init(input, WithDensityThreshold(2))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ctx.GenerateItemsets(0.001); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -74,19 +74,61 @@ func (it *Itemizer) filter(tokens []string, filter func(Item) bool) []Item {
 
 func (it *Itemizer) forEachItem(tokens []string, fn func(Item)) {
 	for _, val := range tokens {
-		val = strings.TrimSpace(val)
+		val = trimToken(val)
 		if len(val) == 0 {
 			continue
 		}
-		itemID, found := it.strToItem[val]
-		if !found {
-			it.numItems++
-			itemID = Item(it.numItems)
-			it.strToItem[val] = itemID
-			it.itemToStr[itemID] = val
+		fn(it.item(val))
+	}
+}
+
+// trimToken returns the item string represented by an input token.
+func trimToken(token string) string {
+	return strings.TrimSpace(token)
+}
+
+// item returns the Item representing val, adding it if it hasn't been seen
+// before.
+func (it *Itemizer) item(val string) Item {
+	itemID, found := it.strToItem[val]
+	if !found {
+		it.numItems++
+		itemID = Item(it.numItems)
+		it.strToItem[val] = itemID
+		it.itemToStr[itemID] = val
+	}
+	return itemID
+}
+
+// reuse adds val to the Itemizer as the Item free, which must have been
+// removed.
+func (it *Itemizer) reuse(val string, free Item) {
+	it.strToItem[val] = free
+	it.itemToStr[free] = val
+}
+
+// remove removes item from the Itemizer. Its Item may then be reused.
+func (it *Itemizer) remove(item Item) {
+	delete(it.strToItem, it.itemToStr[item])
+	delete(it.itemToStr, item)
+}
+
+// itemizeReusing converts values to Items like Itemize, but gives new values
+// Items popped from free, which must have been removed, while there are any.
+func (it *Itemizer) itemizeReusing(values []string, free *[]Item) []Item {
+	items := make([]Item, 0, len(values))
+	for _, val := range values {
+		val = trimToken(val)
+		if len(val) == 0 {
+			continue
 		}
-		fn(itemID)
+		if _, found := it.strToItem[val]; !found && len(*free) > 0 {
+			it.reuse(val, (*free)[len(*free)-1])
+			*free = (*free)[:len(*free)-1]
+		}
+		items = append(items, it.item(val))
 	}
+	return items
 }
 
 func (it *Itemizer) cmp(a Item, b Item) bool {
@@ -100,3 +142,39 @@ func newItemizer() Itemizer {
 		numItems:  0,
 	}
 }
+
+// lookup converts strings to Items without adding new items to the
+// Itemizer. Strings which haven't been seen before are skipped.
+func (it *Itemizer) lookup(values []string) []Item {
+	items := make([]Item, 0, len(values))
+	for _, val := range values {
+		if item, found := it.strToItem[trimToken(val)]; found {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// lookupAll returns the set of Items representing values, skipping strings
+// which haven't been seen before.
+func (it *Itemizer) lookupAll(values []string) map[Item]bool {
+	set := make(map[Item]bool)
+	for _, item := range it.lookup(values) {
+		set[item] = true
+	}
+	return set
+}
+
+// clone returns a copy of the Itemizer which doesn't share state with it.
+func (it *Itemizer) clone() Itemizer {
+	c := Itemizer{
+		strToItem: make(map[string]Item, len(it.strToItem)),
+		itemToStr: make(map[Item]string, len(it.itemToStr)),
+		numItems:  it.numItems,
+	}
+	for s, item := range it.strToItem {
+		c.strToItem[s] = item
+		c.itemToStr[item] = s
+	}
+	return c
+}
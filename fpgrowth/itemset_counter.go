@@ -0,0 +1,90 @@
+package fpgrowth
+
+import "context"
+
+type counterNode struct {
+	children map[Item]*counterNode
+	// index of the candidate itemset ending at this node, or -1.
+	index int
+}
+
+func newCounterNode() *counterNode {
+	return &counterNode{children: make(map[Item]*counterNode), index: -1}
+}
+
+// itemsetCounter counts occurrences of a set of candidate itemsets in
+// transactions. Candidates are stored in a prefix tree, so each transaction
+// is matched against all candidates sharing a prefix at once.
+type itemsetCounter struct {
+	root   *counterNode
+	counts []int
+}
+
+// newItemsetCounter creates an itemsetCounter for candidates, each of which
+// must be sorted. Counts are reported in the same order as candidates.
+func newItemsetCounter(candidates [][]Item) *itemsetCounter {
+	c := &itemsetCounter{
+		root:   newCounterNode(),
+		counts: make([]int, len(candidates)),
+	}
+	for idx, candidate := range candidates {
+		node := c.root
+		for _, item := range candidate {
+			child, found := node.children[item]
+			if !found {
+				child = newCounterNode()
+				node.children[item] = child
+			}
+			node = child
+		}
+		node.index = idx
+	}
+	return c
+}
+
+// add counts the candidates contained in transaction, which must be sorted
+// and contain no duplicates.
+func (c *itemsetCounter) add(transaction []Item, count int) {
+	c.visit(c.root, transaction, count)
+}
+
+func (c *itemsetCounter) visit(node *counterNode, transaction []Item, count int) {
+	for i, item := range transaction {
+		child, found := node.children[item]
+		if !found {
+			continue
+		}
+		if child.index >= 0 {
+			c.counts[child.index] += count
+		}
+		if len(child.children) > 0 {
+			c.visit(child, transaction[i+1:], count)
+		}
+	}
+}
+
+// countItemsets counts the number of transactions in the Context's dataset
+// which contain each of candidates, in a single pass. Stops and returns
+// c.Err() if c is cancelled.
+func (ctx *Context) countItemsets(c context.Context, candidates [][]Item) ([]int, error) {
+	counter := newItemsetCounter(candidates)
+	err := ctx.forEachItemset(c, func(transaction []Item, count int) {
+		counter.add(transaction, count)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counter.counts, nil
+}
+
+// dedupeSorted removes adjacent duplicates from a sorted slice, in place.
+func dedupeSorted(items []Item) []Item {
+	n := 0
+	for i, item := range items {
+		if i == 0 || item != items[n-1] {
+			items[n] = item
+			n++
+		}
+	}
+	return items[:n]
+}
@@ -0,0 +1,125 @@
+package fpgrowth
+
+// itemsetTable maps itemsets to counts. It's a hash table keyed on the items
+// of the sorted itemsets, so unlike a map keyed on a string form of the
+// itemsets, lookups don't allocate.
+type itemsetTable struct {
+	itemsets []ItemsetWithCount
+	// slots stores one more than the index into itemsets of the itemset in
+	// each slot, or zero if the slot is empty. Collisions are resolved by
+	// probing the following slots.
+	slots []int32
+}
+
+func newItemsetTable(capacity int) *itemsetTable {
+	numSlots := 16
+	for numSlots < 2*capacity {
+		numSlots *= 2
+	}
+	return &itemsetTable{
+		itemsets: make([]ItemsetWithCount, 0, capacity),
+		slots:    make([]int32, numSlots),
+	}
+}
+
+// hashItemset returns the FNV-1a hash of itemset's items.
+func hashItemset(itemset []Item) uint64 {
+	h := uint64(14695981039346656037)
+	for _, item := range itemset {
+		h ^= uint64(item)
+		h *= 1099511628211
+	}
+	return h ^ h>>32
+}
+
+// slot returns the index of the slot containing itemset, or of the empty slot
+// where it would be inserted, and whether itemset was found.
+func (t *itemsetTable) slot(itemset []Item) (int, bool) {
+	mask := uint64(len(t.slots) - 1)
+	for s := hashItemset(itemset) & mask; ; s = (s + 1) & mask {
+		idx := t.slots[s]
+		if idx == 0 {
+			return int(s), false
+		}
+		if itemSliceEquals(t.itemsets[idx-1].Itemset, itemset) {
+			return int(s), true
+		}
+	}
+}
+
+// get returns itemset's count, and whether itemset is in the table.
+func (t *itemsetTable) get(itemset []Item) (int, bool) {
+	s, found := t.slot(itemset)
+	if !found {
+		return 0, false
+	}
+	return t.itemsets[t.slots[s]-1].Count, true
+}
+
+func (t *itemsetTable) contains(itemset []Item) bool {
+	_, found := t.slot(itemset)
+	return found
+}
+
+// set sets itemset's count, adding it to the table if it's not already
+// present. The table retains itemset, so it must not be modified afterwards.
+func (t *itemsetTable) set(itemset []Item, count int) {
+	s, found := t.slot(itemset)
+	if found {
+		t.itemsets[t.slots[s]-1].Count = count
+		return
+	}
+	t.itemsets = append(t.itemsets, ItemsetWithCount{Itemset: itemset, Count: count})
+	t.slots[s] = int32(len(t.itemsets))
+	if 2*len(t.itemsets) > len(t.slots) {
+		t.grow()
+	}
+}
+
+// grow doubles the number of slots, keeping the load factor at most a half.
+func (t *itemsetTable) grow() {
+	t.slots = make([]int32, 2*len(t.slots))
+	mask := uint64(len(t.slots) - 1)
+	for idx, iwc := range t.itemsets {
+		s := hashItemset(iwc.Itemset) & mask
+		for t.slots[s] != 0 {
+			s = (s + 1) & mask
+		}
+		t.slots[s] = int32(idx + 1)
+	}
+}
+
+// supportLookup finds the supports of itemsets, which rule generation needs.
+type supportLookup interface {
+	// lookup returns itemset's support, and whether it's stored.
+	lookup(itemset []Item) (float64, bool)
+}
+
+// itemsetSupportLookup stores the supports of itemsets.
+type itemsetSupportLookup struct {
+	table           *itemsetTable
+	numTransactions float64
+}
+
+func createSupportLookup(
+	itemsets []ItemsetWithCount,
+	numTransactions int,
+) *itemsetSupportLookup {
+	table := newItemsetTable(len(itemsets))
+	for _, iwc := range itemsets {
+		table.set(iwc.Itemset, iwc.Count)
+	}
+	return &itemsetSupportLookup{
+		table:           table,
+		numTransactions: float64(numTransactions),
+	}
+}
+
+// lookup returns itemset's support, and whether it's stored.
+func (isl *itemsetSupportLookup) lookup(itemset []Item) (float64, bool) {
+	count, found := isl.table.get(itemset)
+	if !found {
+		return 0, false
+	}
+	return float64(count) / isl.numTransactions, true
+}
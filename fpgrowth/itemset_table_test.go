@@ -0,0 +1,35 @@
+package fpgrowth
+
+import "testing"
+
+func TestItemsetTable(t *testing.T) {
+	table := newItemsetTable(0)
+	itemsets := make([][]Item, 0)
+	for a := Item(1); a <= 40; a++ {
+		itemsets = append(itemsets, []Item{a})
+		for b := a + 1; b <= 40; b++ {
+			itemsets = append(itemsets, []Item{a, b})
+		}
+	}
+	for i, itemset := range itemsets {
+		table.set(itemset, i)
+	}
+	table.set([]Item{3, 7}, -1)
+	for i, itemset := range itemsets {
+		expected := i
+		if itemSliceEquals(itemset, []Item{3, 7}) {
+			expected = -1
+		}
+		if count, found := table.get(itemset); !found || count != expected {
+			t.Error("Expected ", itemset, " to have count ", expected, ", got ", count, found)
+		}
+	}
+	if len(table.itemsets) != len(itemsets) {
+		t.Error("Expected ", len(itemsets), " itemsets, got ", len(table.itemsets))
+	}
+	for _, missing := range [][]Item{{}, {41}, {7, 3}, {1, 2, 3}} {
+		if table.contains(missing) {
+			t.Error("Didn't expect to find ", missing)
+		}
+	}
+}
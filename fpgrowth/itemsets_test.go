@@ -1,6 +1,7 @@
 package fpgrowth
 
 import (
+	"context"
 	"testing"
 )
 
@@ -99,12 +100,16 @@ func TestFPGrowth(t *testing.T) {
 	}
 
 	input := "../datasets/kosarak.csv"
-	itemizer, frequency, numTransactions, err := countItems(input)
+	itemizer, frequency, numTransactions, err := countItems(input, false)
 	if err != nil {
 		t.Error(err)
 	}
 	itemsets, err := generateFrequentItemsets(
-		input,
+		context.Background(),
+		[]string{input},
+		false,
+		1,
+		nil,
 		0.05,
 		itemizer,
 		frequency,
@@ -0,0 +1,34 @@
+package fpgrowth
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger logs structured records of each phase of the analysis, such as
+// how long it took and how many itemsets it generated, to logger. Most
+// records are logged at slog.LevelDebug. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// discardHandler drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// log returns the logger passed to WithLogger(), or one which discards
+// everything.
+func (o options) log() *slog.Logger {
+	if o.logger == nil {
+		return discardLogger
+	}
+	return o.logger
+}
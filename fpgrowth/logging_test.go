@@ -0,0 +1,60 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	path := writeTestCsv(t, randomTransactions(200, 11)...)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx, err := Init(path, WithLogger(logger), WithDensityThreshold(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := ctx.GenerateItemsets(0.05)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := ctx.GenerateRules(itemsets, 0.1, 1)
+
+	records := make(map[string]map[string]any)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		record := make(map[string]any)
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records[record["msg"].(string)] = record
+	}
+	expected := map[string]map[string]any{
+		"counted items":  {"phase": string(PhaseCounting), "transactions": 200.0},
+		"selected miner": {"dense": false},
+		"built FP-tree":  {"phase": string(PhaseBuildingTree)},
+		"mined itemsets": {"phase": string(PhaseMining), "itemsets": float64(len(itemsets))},
+		"generated rules": {
+			"phase":    string(PhaseRules),
+			"itemsets": float64(len(itemsets)),
+			"rules":    float64(len(rules)),
+		},
+	}
+	for msg, attrs := range expected {
+		record, found := records[msg]
+		if !found {
+			t.Error("Expected a record ", msg)
+			continue
+		}
+		for key, value := range attrs {
+			if record[key] != value {
+				t.Error("Expected ", msg, " record's ", key, " to be ", value, ", got ", record[key])
+			}
+		}
+		if _, found := record["duration"]; !found && msg != "selected miner" {
+			t.Error("Expected ", msg, " record to have a duration")
+		}
+	}
+}
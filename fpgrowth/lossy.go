@@ -0,0 +1,234 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+)
+
+type lossyEntry struct {
+	itemset []Item
+	// count is the number of occurrences since the entry was created.
+	count int
+	// maxError bounds the occurrences before the entry was created.
+	maxError int
+}
+
+// LossyCounter approximately counts frequent itemsets over an unbounded
+// stream of transactions using the Lossy Counting algorithm, in memory
+// bounded by the error tolerance rather than the length of the stream. Items
+// are forgotten once no tracked itemset contains them, so memory doesn't grow
+// with the number of distinct items either.
+//
+// Transactions are buffered into batches. Each batch is mined with FP-growth
+// for itemsets occurring in at least epsilon of the batch, and the counts of
+// already tracked itemsets are updated. Tracked itemsets whose maximum
+// possible count falls below epsilon of the stream are then discarded. Every
+// itemset's reported count underestimates its true count by at most epsilon
+// times the number of transactions seen, rounded up to a whole batch, and an
+// itemset's count is only reported as zero if it occurs in at most that many
+// transactions.
+//
+// It's safe to add transactions and query itemsets concurrently.
+type LossyCounter struct {
+	mutex     sync.Mutex
+	epsilon   float64
+	batchSize int
+	itemizer  Itemizer
+	entries   map[string]*lossyEntry
+	// free stores the Items of forgotten items, for reuse by new items.
+	free            []Item
+	buffer          [][]Item
+	numTransactions int
+}
+
+// NewLossyCounter creates a LossyCounter with error tolerance epsilon, which
+// mines batches of batchSize transactions at once. Larger batches use more
+// memory but are faster to process. If batchSize is 0 it defaults to
+// 10/epsilon transactions.
+func NewLossyCounter(epsilon float64, batchSize int) *LossyCounter {
+	if batchSize <= 0 {
+		batchSize = int(math.Ceil(10 / epsilon))
+	}
+	return &LossyCounter{
+		epsilon:   epsilon,
+		batchSize: batchSize,
+		itemizer:  newItemizer(),
+		entries:   make(map[string]*lossyEntry),
+		buffer:    make([][]Item, 0, batchSize),
+	}
+}
+
+// Add adds a transaction to the stream.
+func (lc *LossyCounter) Add(items []string) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	transaction := lc.itemizer.itemizeReusing(items, &lc.free)
+	sort.Slice(transaction, func(i, j int) bool {
+		return transaction[i] < transaction[j]
+	})
+	lc.buffer = append(lc.buffer, dedupeSorted(transaction))
+	if len(lc.buffer) >= lc.batchSize {
+		lc.flush()
+	}
+}
+
+// Consume adds transactions received on the channel until it's closed.
+func (lc *LossyCounter) Consume(transactions <-chan []string) {
+	for t := range transactions {
+		lc.Add(t)
+	}
+}
+
+// batchMinCount returns the number of occurrences in a batch above which an
+// itemset is tracked; epsilon of a full batch.
+func (lc *LossyCounter) batchMinCount() int {
+	return max(1, int(math.Ceil(lc.epsilon*float64(lc.batchSize))))
+}
+
+// countBuffer returns the number of occurrences of each tracked itemset in the
+// buffered transactions, and the itemsets which occur at least
+// batchMinCount() times in them.
+func (lc *LossyCounter) countBuffer() ([]*lossyEntry, []int, []ItemsetWithCount) {
+	tracked := make([]*lossyEntry, 0, len(lc.entries))
+	candidates := make([][]Item, 0, len(lc.entries))
+	for _, e := range lc.entries {
+		tracked = append(tracked, e)
+		candidates = append(candidates, e.itemset)
+	}
+	counter := newItemsetCounter(candidates)
+	tree := newTree()
+	for _, transaction := range lc.buffer {
+		counter.add(transaction, 1)
+		tree.Insert(transaction, 1)
+	}
+	return tracked, counter.counts, fpGrowth(tree, make([]Item, 0), lc.batchMinCount())
+}
+
+// flush processes the buffered batch of transactions.
+func (lc *LossyCounter) flush() {
+	previous := lc.numTransactions
+	lc.numTransactions += len(lc.buffer)
+
+	// Update counts of the tracked itemsets, and track itemsets occurring in
+	// at least epsilon of the batch. Any itemset that's not tracked occurred
+	// fewer times than that.
+	tracked, counts, frequent := lc.countBuffer()
+	for i, e := range tracked {
+		e.count += counts[i]
+	}
+	maxError := int(lc.epsilon * float64(previous))
+	for _, iwc := range frequent {
+		key := itemSliceKey(iwc.Itemset)
+		if _, found := lc.entries[key]; !found {
+			lc.entries[key] = &lossyEntry{
+				itemset:  iwc.Itemset,
+				count:    iwc.Count,
+				maxError: maxError,
+			}
+		}
+	}
+
+	// Discard itemsets which can't have occurred in more than epsilon of the
+	// stream.
+	bound := lc.epsilon * float64(lc.numTransactions)
+	for key, e := range lc.entries {
+		if float64(e.count+e.maxError) <= bound {
+			delete(lc.entries, key)
+		}
+	}
+	lc.buffer = lc.buffer[:0]
+
+	// Forget items which aren't in any tracked itemset.
+	inUse := make(map[Item]bool)
+	for _, e := range lc.entries {
+		for _, item := range e.itemset {
+			inUse[item] = true
+		}
+	}
+	for item := range lc.itemizer.itemToStr {
+		if !inUse[item] {
+			lc.itemizer.remove(item)
+			lc.free = append(lc.free, item)
+		}
+	}
+}
+
+// Len returns the number of transactions added to the stream.
+func (lc *LossyCounter) Len() int {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	return lc.numTransactions + len(lc.buffer)
+}
+
+// Itemsets returns the itemsets which may have support of at least
+// minSupport in the stream so far, which should be greater than epsilon.
+// Guaranteed itemsets are certainly frequent. Possible itemsets may be
+// frequent, depending on the counting error. Every frequent itemset is in one
+// of the two. Counts are lower bounds on the true counts, and are at most
+// epsilon times Len(), rounded up to a whole batch, below them.
+//
+// Transactions in the incomplete batch are counted, but not mined at a lower
+// threshold than a full batch would be, so querying uses no more memory than
+// adding a batch does.
+func (lc *LossyCounter) Itemsets(
+	minSupport float64,
+) (guaranteed []ItemsetWithCount, possible []ItemsetWithCount) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	guaranteed = make([]ItemsetWithCount, 0)
+	possible = make([]ItemsetWithCount, 0)
+	minCount := minSupport * float64(lc.numTransactions+len(lc.buffer))
+	add := func(itemset []Item, count int, maxError int) {
+		iwc := ItemsetWithCount{Itemset: itemset, Count: count}
+		if float64(count) >= minCount {
+			guaranteed = append(guaranteed, iwc)
+		} else if float64(count+maxError) >= minCount {
+			possible = append(possible, iwc)
+		}
+	}
+	tracked, counts, frequent := lc.countBuffer()
+	for i, e := range tracked {
+		add(e.itemset, e.count+counts[i], e.maxError)
+	}
+	maxError := int(lc.epsilon * float64(lc.numTransactions))
+	for _, iwc := range frequent {
+		if _, found := lc.entries[itemSliceKey(iwc.Itemset)]; !found {
+			add(iwc.Itemset, iwc.Count, maxError)
+		}
+	}
+	return guaranteed, possible
+}
+
+// WriteItemsets writes itemsets returned by Itemsets() to CSV file, with their
+// approximate support, and the maximum error in that support. Items may be
+// forgotten once a batch is processed, so no transactions may be added between
+// calling Itemsets() and WriteItemsets().
+func (lc *LossyCounter) WriteItemsets(
+	itemsets []ItemsetWithCount,
+	filePath string,
+) error {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	output, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Itemset,Support,MaxError")
+	n := math.Max(1, float64(lc.numTransactions+len(lc.buffer)))
+	for _, iwc := range itemsets {
+		writeItemset(w, &lc.itemizer, iwc.Itemset)
+		// Itemsets which aren't tracked were found in the incomplete batch.
+		maxError := int(lc.epsilon * float64(lc.numTransactions))
+		if e, found := lc.entries[itemSliceKey(iwc.Itemset)]; found {
+			maxError = e.maxError
+		}
+		fmt.Fprintf(w, ",%f,%f\n", float64(iwc.Count)/n, float64(maxError)/n)
+	}
+	return w.Flush()
+}
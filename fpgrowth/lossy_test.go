@@ -0,0 +1,103 @@
+package fpgrowth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLossyCounter(t *testing.T) {
+	const epsilon = 0.02
+	const minSupport = 0.1
+	lines := randomTransactions(1000, 3)
+	exactCtx, err := Init(writeTestCsv(t, lines...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exactItemsets, err := exactCtx.GenerateItemsets(epsilon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exact := itemsetCounts(exactCtx, exactItemsets)
+
+	lc := NewLossyCounter(epsilon, 150)
+	for _, line := range lines {
+		lc.Add(strings.Split(line, ","))
+	}
+	if lc.Len() != len(lines) {
+		t.Error("Expected ", len(lines), " transactions, got ", lc.Len())
+	}
+	guaranteed, possible := lc.Itemsets(minSupport)
+
+	ctx := Context{itemizer: lc.itemizer, numTransactions: lc.Len()}
+	g := itemsetCounts(ctx, guaranteed)
+	p := itemsetCounts(ctx, possible)
+	maxError := int(epsilon * float64(len(lines)))
+	minCount := int(minSupport * float64(len(lines)))
+	for key, count := range exact {
+		reported, isGuaranteed := g[key]
+		possibleCount, isPossible := p[key]
+		if isPossible {
+			reported = possibleCount
+		}
+		if count >= minCount && !isGuaranteed && !isPossible {
+			t.Error("Frequent itemset ", key, " with count ", count, " not reported")
+		}
+		if (isGuaranteed || isPossible) &&
+			(reported > count || reported < count-maxError) {
+			t.Error("Itemset ", key, " count ", reported, " not within error of ", count)
+		}
+	}
+	for key := range g {
+		if exact[key] < minCount {
+			t.Error("Guaranteed itemset ", key, " isn't frequent")
+		}
+	}
+	if len(g) == 0 || len(g) > len(exact) {
+		t.Error("Unexpected number of guaranteed itemsets ", len(g))
+	}
+}
+
+func TestLossyCounterIncompleteBatch(t *testing.T) {
+	lc := NewLossyCounter(0.1, 0)
+	for i := 0; i < 50; i++ {
+		lc.Add([]string{"a", "b", "c"})
+	}
+	// Long transactions, whose 2^20 subsets mustn't all be mined.
+	long := make([]string, 20)
+	for i := range long {
+		long[i] = fmt.Sprint("x", i)
+	}
+	for i := 0; i < 3; i++ {
+		lc.Add(long)
+	}
+	guaranteed, possible := lc.Itemsets(0.5)
+	if len(guaranteed) != 7 || len(possible) != 0 {
+		t.Error("Expected the 7 subsets of a b c, got ", len(guaranteed), " and ", len(possible))
+	}
+	for _, iwc := range guaranteed {
+		if iwc.Count != 50 {
+			t.Error("Expected count 50, got ", iwc)
+		}
+	}
+	// Querying doesn't process the incomplete batch.
+	if len(lc.entries) != 0 || len(lc.buffer) != 53 || lc.Len() != 53 {
+		t.Error("Querying changed the counter's state")
+	}
+}
+
+func TestLossyCounterForgetsItems(t *testing.T) {
+	lc := NewLossyCounter(0.1, 20)
+	for i := 0; i < 1000; i++ {
+		lc.Add([]string{"a", fmt.Sprint("x", i)})
+	}
+	// Only a is tracked, along with the items of the incomplete batch.
+	if n := len(lc.itemizer.strToItem); n > 1+lc.batchSize {
+		t.Error("Expected rare items to be forgotten, but ", n, " are held")
+	}
+	guaranteed, _ := lc.Itemsets(0.5)
+	if len(guaranteed) != 1 || lc.itemizer.ToStr(guaranteed[0].Itemset[0]) != "a" ||
+		guaranteed[0].Count != 1000 {
+		t.Error("Expected a to occur 1000 times, got ", guaranteed)
+	}
+}
@@ -0,0 +1,298 @@
+package fpgrowth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Miner generates the frequent itemsets of a Context's dataset. All Miners
+// generate the same itemsets with the same counts, but their performance
+// differs depending on the dataset.
+type Miner interface {
+	Mine(ctx Context, minSupport float64) (GeneratedItemsets, error)
+	// MineContext is like Mine, but stops and returns c.Err() soon after c
+	// is cancelled.
+	MineContext(
+		c context.Context,
+		ctx Context,
+		minSupport float64,
+	) (GeneratedItemsets, error)
+}
+
+// FPGrowth mines itemsets by building an FP-tree of the frequent items of
+// each transaction, and recursively mining conditional FP-trees. This is what
+// Context.GenerateItemsets() uses for sparse datasets.
+type FPGrowth struct{}
+
+// Apriori mines itemsets level by level. Candidate itemsets of size k+1 are
+// generated from the frequent itemsets of size k, and counted with a pass
+// over the dataset.
+type Apriori struct{}
+
+// Eclat mines itemsets from a vertical representation of the dataset, which
+// stores the list of transactions containing each item. Itemsets' transaction
+// lists are intersected depth first. If Diffsets is true, the dEclat variant
+// is used, which stores the difference between an itemset's transaction list
+// and its prefix's, which is much smaller on dense datasets.
+type Eclat struct {
+	Diffsets bool
+}
+
+// MinerByName returns the Miner called name, one of "fpgrowth", "apriori",
+// "eclat", "declat" or "bitset".
+func MinerByName(name string) (Miner, error) {
+	switch name {
+	case "fpgrowth":
+		return FPGrowth{}, nil
+	case "apriori":
+		return Apriori{}, nil
+	case "eclat":
+		return Eclat{}, nil
+	case "declat":
+		return Eclat{Diffsets: true}, nil
+	case "bitset":
+		return Bitset{}, nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", name)
+}
+
+// Mine generates frequent itemsets with support above minSupport.
+func (f FPGrowth) Mine(ctx Context, minSupport float64) (GeneratedItemsets, error) {
+	return f.MineContext(context.Background(), ctx, minSupport)
+}
+
+// MineContext generates frequent itemsets with support above minSupport,
+// stopping if c is cancelled.
+func (FPGrowth) MineContext(
+	c context.Context,
+	ctx Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	return ctx.fpGrowth(c, minSupport)
+}
+
+// cancelled reports whether c is done, without blocking.
+func cancelled(c context.Context) bool {
+	select {
+	case <-c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func frequentItems(ctx *Context, minCount int) []Item {
+	items := make([]Item, 0)
+	for item := Item(1); int(item) <= ctx.itemizer.numItems; item++ {
+		if ctx.frequency.get(item) >= minCount {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Mine generates frequent itemsets with support above minSupport.
+func (a Apriori) Mine(ctx Context, minSupport float64) (GeneratedItemsets, error) {
+	return a.MineContext(context.Background(), ctx, minSupport)
+}
+
+// MineContext generates frequent itemsets with support above minSupport,
+// stopping if c is cancelled.
+func (Apriori) MineContext(
+	c context.Context,
+	ctx Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	minCount := minCountFor(minSupport, ctx.numTransactions)
+	progress := startProgress(ctx.options.progress, PhaseMining, 0)
+	defer progress.finish()
+	itemsets := make(GeneratedItemsets, 0)
+	known := newItemsetTable(0)
+	level := make([][]Item, 0)
+	for _, item := range frequentItems(&ctx, minCount) {
+		itemset := []Item{item}
+		itemsets = append(itemsets, ItemsetWithCount{itemset, ctx.frequency.get(item)})
+		known.set(itemset, ctx.frequency.get(item))
+		level = append(level, itemset)
+	}
+	for len(level) > 0 {
+		progress.add(1)
+		progress.addItemsets(len(level))
+		if cancelled(c) {
+			return nil, c.Err()
+		}
+		candidates := aprioriGen(level, known)
+		if len(candidates) == 0 {
+			break
+		}
+		counts, err := ctx.countItemsets(c, candidates)
+		if err != nil {
+			return nil, err
+		}
+		level = make([][]Item, 0)
+		for i, candidate := range candidates {
+			if counts[i] < minCount {
+				continue
+			}
+			itemsets = append(itemsets, ItemsetWithCount{candidate, counts[i]})
+			known.set(candidate, counts[i])
+			level = append(level, candidate)
+		}
+	}
+	return itemsets, nil
+}
+
+// eclatNode is a member of an equivalence class of itemsets sharing a
+// prefix. tids is either the sorted list of transactions containing the
+// prefix plus item, or its diffset; the transactions containing the prefix
+// but not the prefix plus item.
+type eclatNode struct {
+	item    Item
+	tids    []int
+	support int
+}
+
+type eclatMiner struct {
+	c        context.Context
+	progress *progressTracker
+	// weights stores the count of each transaction.
+	weights  []int
+	minCount int
+	diffsets bool
+	itemsets GeneratedItemsets
+}
+
+func (m *eclatMiner) weight(tids []int) int {
+	w := 0
+	for _, tid := range tids {
+		w += m.weights[tid]
+	}
+	return w
+}
+
+// Mine generates frequent itemsets with support above minSupport.
+func (e Eclat) Mine(ctx Context, minSupport float64) (GeneratedItemsets, error) {
+	return e.MineContext(context.Background(), ctx, minSupport)
+}
+
+// MineContext generates frequent itemsets with support above minSupport,
+// stopping if c is cancelled.
+func (e Eclat) MineContext(
+	c context.Context,
+	ctx Context,
+	minSupport float64,
+) (GeneratedItemsets, error) {
+	minCount := minCountFor(minSupport, ctx.numTransactions)
+	items := frequentItems(&ctx, minCount)
+	index := make(map[Item]int, len(items))
+	class := make([]eclatNode, len(items))
+	for i, item := range items {
+		index[item] = i
+		class[i] = eclatNode{item: item, support: ctx.frequency.get(item)}
+	}
+	progress := startProgress(ctx.options.progress, PhaseMining, int64(len(class)))
+	defer progress.finish()
+	m := &eclatMiner{
+		c:        c,
+		progress: progress,
+		weights:  make([]int, 0),
+		minCount: minCount,
+		diffsets: e.Diffsets,
+		itemsets: make(GeneratedItemsets, 0),
+	}
+	err := ctx.forEachItemset(c, func(transaction []Item, count int) {
+		tid := len(m.weights)
+		m.weights = append(m.weights, count)
+		for _, item := range transaction {
+			if i, found := index[item]; found {
+				class[i].tids = append(class[i].tids, tid)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Processing items in increasing order of support keeps the classes of
+	// less frequent items, which have shorter transaction lists, largest.
+	sort.SliceStable(class, func(i, j int) bool {
+		return class[i].support < class[j].support
+	})
+	m.mine(make([]Item, 0), class, false)
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return m.itemsets, nil
+}
+
+func (m *eclatMiner) mine(prefix []Item, class []eclatNode, isDiffset bool) {
+	for i, x := range class {
+		if cancelled(m.c) {
+			return
+		}
+		itemset := appendSorted(prefix, x.item)
+		m.itemsets = append(m.itemsets, ItemsetWithCount{itemset, x.support})
+		m.progress.addItemsets(1)
+		next := make([]eclatNode, 0, len(class)-i-1)
+		for _, y := range class[i+1:] {
+			var node eclatNode
+			switch {
+			case isDiffset:
+				// d(PXY) = d(PY) - d(PX)
+				tids := differenceInts(y.tids, x.tids)
+				node = eclatNode{y.item, tids, x.support - m.weight(tids)}
+			case m.diffsets:
+				// d(XY) = t(X) - t(Y)
+				tids := differenceInts(x.tids, y.tids)
+				node = eclatNode{y.item, tids, x.support - m.weight(tids)}
+			default:
+				tids := intersectInts(x.tids, y.tids)
+				node = eclatNode{y.item, tids, m.weight(tids)}
+			}
+			if node.support >= m.minCount {
+				next = append(next, node)
+			}
+		}
+		if len(next) > 0 {
+			m.mine(itemset, next, isDiffset || m.diffsets)
+		}
+		if len(prefix) == 0 {
+			m.progress.add(1)
+		}
+	}
+}
+
+func intersectInts(a, b []int) []int {
+	c := make([]int, 0, min(len(a), len(b)))
+	ai, bi := 0, 0
+	for ai < len(a) && bi < len(b) {
+		if a[ai] < b[bi] {
+			ai++
+		} else if b[bi] < a[ai] {
+			bi++
+		} else {
+			c = append(c, a[ai])
+			ai++
+			bi++
+		}
+	}
+	return c
+}
+
+// differenceInts returns the elements of a which aren't in b.
+func differenceInts(a, b []int) []int {
+	c := make([]int, 0, len(a))
+	ai, bi := 0, 0
+	for ai < len(a) {
+		if bi == len(b) || a[ai] < b[bi] {
+			c = append(c, a[ai])
+			ai++
+		} else if b[bi] < a[ai] {
+			bi++
+		} else {
+			ai++
+			bi++
+		}
+	}
+	return c
+}
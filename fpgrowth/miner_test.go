@@ -0,0 +1,175 @@
+package fpgrowth
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMiners(t *testing.T) {
+	lines := randomTransactions(500, 5)
+	weighted := make([]string, 0)
+	for i := 0; i < len(lines); i += 5 {
+		weighted = append(weighted, "3,"+lines[i])
+	}
+	contexts := make([]Context, 0)
+	for _, tc := range []struct {
+		lines []string
+		opts  []Option
+	}{
+		{lines, nil},
+		{weighted, []Option{WithWeightedTransactions()}},
+		{lines, []Option{WithIncrementalMining()}},
+	} {
+		ctx, err := Init(writeTestCsv(t, tc.lines...), tc.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contexts = append(contexts, ctx)
+	}
+
+	for _, name := range []string{"apriori", "eclat", "declat"} {
+		miner, err := MinerByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, ctx := range contexts {
+			for _, minSupport := range []float64{0.02, 0.1, 0.4} {
+				expected, err := FPGrowth{}.Mine(ctx, minSupport)
+				if err != nil {
+					t.Fatal(err)
+				}
+				observed, err := miner.Mine(ctx, minSupport)
+				if err != nil {
+					t.Fatal(err)
+				}
+				e := itemsetCounts(ctx, expected)
+				o := itemsetCounts(ctx, observed)
+				if !equalCounts(e, o) {
+					t.Error(name, " context ", i, " minSupport=", minSupport,
+						": itemsets ", o, " don't match fpgrowth ", e)
+				}
+				if len(observed) != len(expected) {
+					t.Error(name, " generated duplicate itemsets")
+				}
+			}
+		}
+	}
+
+	if _, err := MinerByName("magic"); err == nil {
+		t.Error("Expected error for unknown algorithm")
+	}
+}
+
+// denseTransactions generates n transactions over a small alphabet, where
+// each item occurs in most transactions.
+func denseTransactions(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	items := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	lines := make([]string, n)
+	for i := range lines {
+		transaction := make([]string, 0)
+		for _, item := range items {
+			if rng.Intn(10) < 7 {
+				transaction = append(transaction, item)
+			}
+		}
+		lines[i] = strings.Join(transaction, ",")
+	}
+	return lines
+}
+
+// sparseTransactions generates n transactions of three distinct items drawn
+// from a large alphabet.
+func sparseTransactions(n int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	lines := make([]string, n)
+	for i := range lines {
+		p := rng.Perm(40)
+		lines[i] = fmt.Sprintf("i%d,i%d,i%d", p[0], p[1], p[2])
+	}
+	return lines
+}
+
+// phaseRecorder records the phases reported to it.
+type phaseRecorder map[Phase]bool
+
+func (r phaseRecorder) Report(p Progress) {
+	r[p.Phase] = true
+}
+
+func TestSparseDatasetUsesFPGrowth(t *testing.T) {
+	// A sparse dataset, with one item in half of the transactions.
+	lines := sparseTransactions(300, 6)
+	for i := 0; i < len(lines); i += 2 {
+		lines[i] += ",hot"
+	}
+	phases := make(phaseRecorder)
+	sparse, err := Init(writeTestCsv(t, lines...), WithProgress(phases))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := sparse.density(); math.Abs(d-3.5/41) > 1e-9 {
+		t.Error("Expected density 3.5/41, got ", d)
+	}
+	// Each frequent item occurs in at least minSupport of transactions, so
+	// at a high minSupport, the frequent items alone are dense. The miner is
+	// chosen by the density of the whole dataset.
+	const minSupport = 0.3
+	if d := sparse.frequentDensity(minCountFor(minSupport, 300)); d < defaultDensityThreshold {
+		t.Error("Expected frequent items to be dense, got ", d)
+	}
+	itemsets, err := sparse.GenerateItemsets(minSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itemsets) != 1 {
+		t.Error("Expected 1 itemset, got ", itemsets)
+	}
+	if !phases[PhaseBuildingTree] {
+		t.Error("Expected FP-growth to be used at minSupport=", minSupport)
+	}
+}
+
+func TestBitsetMiner(t *testing.T) {
+	dense, err := Init(writeTestCsv(t, denseTransactions(300, 6)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, err := Init(writeTestCsv(t, sparseTransactions(300, 6)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	minSupports := []float64{0.01, 0.3}
+	if d := dense.density(); d < defaultDensityThreshold {
+		t.Error("Expected dense dataset, density ", d)
+	}
+	if d := sparse.density(); d >= defaultDensityThreshold {
+		t.Error("Expected sparse dataset, density ", d)
+	}
+	for _, ctx := range []Context{dense, sparse} {
+		for _, minSupport := range minSupports {
+			expected, err := FPGrowth{}.Mine(ctx, minSupport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, mine := range []func() (GeneratedItemsets, error){
+				func() (GeneratedItemsets, error) { return Bitset{}.Mine(ctx, minSupport) },
+				func() (GeneratedItemsets, error) { return ctx.GenerateItemsets(minSupport) },
+			} {
+				observed, err := mine()
+				if err != nil {
+					t.Fatal(err)
+				}
+				e := itemsetCounts(ctx, expected)
+				o := itemsetCounts(ctx, observed)
+				if !equalCounts(e, o) || len(observed) != len(expected) {
+					t.Error("minSupport=", minSupport, ": itemsets ", o,
+						" don't match fpgrowth ", e)
+				}
+			}
+		}
+	}
+}
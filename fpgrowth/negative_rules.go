@@ -0,0 +1,184 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+)
+
+// NegativeRule represents an association rule where the antecedent and/or
+// consequent is negated, i.e. the absence of the items rather than their
+// presence. For example "buys X implies doesn't buy Y" has a negated
+// consequent. Support, confidence and lift are computed over the negated
+// itemsets, so for X => !Y support is supp(X) - supp(X ∪ Y).
+type NegativeRule struct {
+	Rule
+	NegatedAntecedent bool
+	NegatedConsequent bool
+}
+
+// maxNegativeRuleItems is the largest itemset split into antecedent and
+// consequent pairs for negative rules. An itemset of k items has 2^k-2 splits,
+// so longer itemsets are skipped.
+const maxNegativeRuleItems = 12
+
+// GenerateNegativeRules generates negative association rules from itemsets
+// with confidence/lift above minConfidence/minLift. Each itemset X ∪ Y with
+// at least two items is split into each possible antecedent and consequent
+// pair X, Y which are both frequent, and the rules X => !Y, !X => Y and
+// !X => !Y are considered. As well as the frequent itemsets, the unions
+// considered include their negative border, the itemsets which aren't
+// frequent but all of whose subsets are. These find substitutes, such as two
+// brands which are each frequent but rarely bought together. The negative
+// border is counted in one pass over the dataset. Itemsets of more than 12
+// items aren't split.
+func (ctx Context) GenerateNegativeRules(
+	itemsets GeneratedItemsets,
+	minConfidence float64,
+	minLift float64,
+) ([]NegativeRule, error) {
+	return ctx.GenerateNegativeRulesContext(context.Background(), itemsets, minConfidence, minLift)
+}
+
+// GenerateNegativeRulesContext is like GenerateNegativeRules, but stops and
+// returns c.Err() soon after c is cancelled.
+func (ctx Context) GenerateNegativeRulesContext(
+	c context.Context,
+	itemsets GeneratedItemsets,
+	minConfidence float64,
+	minLift float64,
+) ([]NegativeRule, error) {
+	frequent := make([][]Item, len(itemsets))
+	for i, iwc := range itemsets {
+		frequent[i] = iwc.Itemset
+	}
+	border := make([][]Item, 0)
+	for _, itemset := range negativeBorder(frequent) {
+		if len(itemset) <= maxNegativeRuleItems {
+			border = append(border, itemset)
+		}
+	}
+	counts, err := ctx.countItemsets(c, border)
+	if err != nil {
+		return nil, err
+	}
+	unions := make([]ItemsetWithCount, len(border))
+	for i, itemset := range border {
+		unions[i] = ItemsetWithCount{itemset, counts[i]}
+	}
+	return generateNegativeRules(
+		itemsets,
+		unions,
+		ctx.numTransactions,
+		minConfidence,
+		minLift,
+	), nil
+}
+
+// generateNegativeRules generates negative rules by splitting each of
+// itemsets, and of infrequent, into frequent antecedent and consequent
+// itemsets. Itemsets must be downward closed, so the supports of the splits
+// can be looked up in them.
+func generateNegativeRules(
+	itemsets []ItemsetWithCount,
+	infrequent []ItemsetWithCount,
+	numTransactions int,
+	minConfidence float64,
+	minLift float64,
+) []NegativeRule {
+	itemsetSupport := createSupportLookup(itemsets, numTransactions)
+	rules := make([]NegativeRule, 0)
+	add := func(a, c []Item, negA, negC bool, support, aSup, cSup float64) {
+		if aSup <= 0 || cSup <= 0 {
+			return
+		}
+		confidence := support / aSup
+		lift := confidence / cSup
+		if confidence < minConfidence || lift < minLift {
+			return
+		}
+		rules = append(rules, NegativeRule{
+			Rule:              NewRule(a, c, support, confidence, lift),
+			NegatedAntecedent: negA,
+			NegatedConsequent: negC,
+		})
+	}
+	split := func(itemset ItemsetWithCount) {
+		k := len(itemset.Itemset)
+		if k < 2 || k > maxNegativeRuleItems {
+			return
+		}
+		acSup := float64(itemset.Count) / float64(numTransactions)
+		for mask := 1; mask < (1<<k)-1; mask++ {
+			a := make([]Item, 0, k-1)
+			c := make([]Item, 0, k-1)
+			for i, item := range itemset.Itemset {
+				if mask&(1<<i) != 0 {
+					a = append(a, item)
+				} else {
+					c = append(c, item)
+				}
+			}
+			aSup, aFound := itemsetSupport.lookup(a)
+			cSup, cFound := itemsetSupport.lookup(c)
+			if !aFound || !cFound {
+				continue
+			}
+			add(a, c, false, true, aSup-acSup, aSup, 1-cSup)
+			add(a, c, true, false, cSup-acSup, 1-aSup, cSup)
+			add(a, c, true, true, 1-aSup-cSup+acSup, 1-aSup, 1-cSup)
+		}
+	}
+	for _, itemset := range itemsets {
+		split(itemset)
+	}
+	for _, itemset := range infrequent {
+		split(itemset)
+	}
+	return rules
+}
+
+func writeMaybeNegatedItemset(
+	w *bufio.Writer,
+	itemizer *Itemizer,
+	itemset []Item,
+	negated bool,
+) {
+	if !negated {
+		writeItemset(w, itemizer, itemset)
+		return
+	}
+	fmt.Fprint(w, "!(")
+	writeItemset(w, itemizer, itemset)
+	fmt.Fprint(w, ")")
+}
+
+// WriteNegativeRules writes negative rules to CSV file. Negated itemsets are
+// written as !(items), so "a => !(b c)" means transactions containing a tend
+// not to contain both b and c.
+func (ctx Context) WriteNegativeRules(
+	outputPath string,
+	rules []NegativeRule,
+) error {
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Antecedent => Consequent,Confidence,Lift,Support")
+	for _, rule := range rules {
+		writeMaybeNegatedItemset(w, &ctx.itemizer, rule.Antecedent, rule.NegatedAntecedent)
+		fmt.Fprint(w, " => ")
+		writeMaybeNegatedItemset(w, &ctx.itemizer, rule.Consequent, rule.NegatedConsequent)
+		fmt.Fprintf(
+			w,
+			",%f,%f,%f\n",
+			rule.Confidence,
+			rule.Lift,
+			rule.Support,
+		)
+	}
+	return w.Flush()
+}
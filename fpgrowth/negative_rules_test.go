@@ -0,0 +1,89 @@
+package fpgrowth
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGenerateNegativeRules(t *testing.T) {
+	itemsets := []ItemsetWithCount{
+		{[]Item{1}, 6},
+		{[]Item{2}, 5},
+		{[]Item{1, 2}, 1},
+	}
+	expected := []NegativeRule{
+		{Rule{[]Item{1}, []Item{2}, 0.5, 0.5 / 0.6, 0.5 / 0.6 / 0.5}, false, true},
+		{Rule{[]Item{1}, []Item{2}, 0.4, 1, 2}, true, false},
+		{Rule{[]Item{2}, []Item{1}, 0.4, 0.8, 2}, false, true},
+		{Rule{[]Item{2}, []Item{1}, 0.5, 1, 1 / 0.6}, true, false},
+	}
+	rules := generateNegativeRules(itemsets, nil, 10, 0.5, 1.5)
+	if len(rules) != len(expected) {
+		t.Fatal("Expected ", len(expected), " rules, got ", rules)
+	}
+	for _, e := range expected {
+		found := false
+		for _, r := range rules {
+			if !ruleEquals(&r.Rule, &e.Rule) ||
+				r.NegatedAntecedent != e.NegatedAntecedent ||
+				r.NegatedConsequent != e.NegatedConsequent {
+				continue
+			}
+			found = true
+			if math.Abs(r.Support-e.Support) > 1e-9 ||
+				math.Abs(r.Confidence-e.Confidence) > 1e-9 ||
+				math.Abs(r.Lift-e.Lift) > 1e-9 {
+				t.Error("Stats don't match for ", r, " expected ", e)
+			}
+		}
+		if !found {
+			t.Error("Expected rule not found, ", e)
+		}
+	}
+}
+
+func TestNegativeRulesInfrequentUnion(t *testing.T) {
+	// a and b are each frequent, but rarely occur together.
+	path := writeTestCsv(t,
+		"a", "a", "a", "a", "a",
+		"b", "b", "b", "b",
+		"a,b",
+	)
+	ctx, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := ctx.GenerateItemsets(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itemsets) != 2 {
+		t.Fatal("Expected a and b to be the only frequent itemsets, got ", itemsets)
+	}
+	rules, err := ctx.GenerateNegativeRules(itemsets, 0.5, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same rules as TestGenerateNegativeRules, as supp(a ∪ b) = 0.1.
+	if len(rules) != 4 {
+		t.Fatal("Expected 4 rules, got ", rules)
+	}
+	for _, r := range rules {
+		if len(r.Antecedent) != 1 || len(r.Consequent) != 1 ||
+			r.NegatedAntecedent == r.NegatedConsequent {
+			t.Error("Unexpected rule ", r)
+		}
+		if r.NegatedConsequent && ctx.itemizer.ToStr(r.Antecedent[0]) == "b" &&
+			math.Abs(r.Confidence-0.8) > 1e-9 {
+			t.Error("Expected b => !a to have confidence 0.8, got ", r.Confidence)
+		}
+	}
+
+	long := make([]Item, maxNegativeRuleItems+1)
+	for i := range long {
+		long[i] = Item(i + 1)
+	}
+	if rules := generateNegativeRules([]ItemsetWithCount{{long, 1}}, nil, 10, 0, 0); len(rules) != 0 {
+		t.Error("Expected itemsets longer than ", maxNegativeRuleItems, " to be skipped")
+	}
+}
@@ -0,0 +1,198 @@
+package fpgrowth
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase identifies a stage of an analysis, for progress reporting.
+type Phase string
+
+const (
+	// PhaseCounting is the first pass over a dataset, counting items.
+	// Progress is measured in bytes of input read.
+	PhaseCounting Phase = "counting items"
+	// PhaseBuildingTree is the pass over the dataset which builds an
+	// FP-tree of its frequent items. Progress is measured in bytes of input
+	// read.
+	PhaseBuildingTree Phase = "building tree"
+	// PhaseMining generates the frequent itemsets. Progress is measured in
+	// frequent items whose itemsets have been generated, except by Apriori,
+	// which measures the number of levels of itemsets generated, and doesn't
+	// know the total.
+	PhaseMining Phase = "mining itemsets"
+	// PhaseRules generates rules. Progress is measured in itemsets whose
+	// rules have been generated.
+	PhaseRules Phase = "generating rules"
+)
+
+// Progress describes how far a phase has got.
+type Progress struct {
+	Phase Phase
+	// Processed is the amount of the phase's work done so far, out of
+	// Total. Total is zero if it isn't known.
+	Processed int64
+	Total     int64
+	// Itemsets and Rules are the number of itemsets and rules generated so
+	// far in the phase.
+	Itemsets int64
+	Rules    int64
+	// TreeNodes is the number of nodes in the FP-tree built, set in the last
+	// report of PhaseBuildingTree.
+	TreeNodes int64
+	Elapsed   time.Duration
+	// Done is set in the last report of a phase, including if the phase
+	// failed or was cancelled.
+	Done bool
+}
+
+// Fraction returns the fraction of the phase's work done, or zero if the
+// total isn't known.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Processed) / float64(p.Total)
+}
+
+// ProgressReporter receives reports of the progress of each phase of an
+// analysis. Reports are made periodically while a phase runs, and once when it
+// finishes. Report is never called concurrently, and should return quickly.
+type ProgressReporter interface {
+	Report(p Progress)
+}
+
+// ProgressFunc adapts a function to a ProgressReporter.
+type ProgressFunc func(p Progress)
+
+// Report calls f(p).
+func (f ProgressFunc) Report(p Progress) {
+	f(p)
+}
+
+// progressInterval is how often running phases report their progress.
+var progressInterval = 100 * time.Millisecond
+
+// progressTracker counts a phase's progress, which can be updated from any
+// goroutine, and periodically reports it. A nil *progressTracker is valid,
+// and ignores updates.
+type progressTracker struct {
+	reporter  ProgressReporter
+	phase     Phase
+	total     int64
+	start     time.Time
+	processed atomic.Int64
+	itemsets  atomic.Int64
+	rules     atomic.Int64
+	treeNodes atomic.Int64
+	stop      chan struct{}
+	stopped   chan struct{}
+	finished  sync.Once
+}
+
+// startProgress starts reporting a phase's progress to reporter, returning
+// nil if reporter is nil. The caller must call finish when the phase ends.
+func startProgress(reporter ProgressReporter, phase Phase, total int64) *progressTracker {
+	if reporter == nil {
+		return nil
+	}
+	t := &progressTracker{
+		reporter: reporter,
+		phase:    phase,
+		total:    total,
+		start:    time.Now(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go func() {
+		defer close(t.stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.reporter.Report(t.progress(false))
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func (t *progressTracker) progress(done bool) Progress {
+	return Progress{
+		Phase:     t.phase,
+		Processed: t.processed.Load(),
+		Total:     t.total,
+		Itemsets:  t.itemsets.Load(),
+		Rules:     t.rules.Load(),
+		TreeNodes: t.treeNodes.Load(),
+		Elapsed:   time.Since(t.start),
+		Done:      done,
+	}
+}
+
+func (t *progressTracker) add(processed int64) {
+	if t != nil {
+		t.processed.Add(processed)
+	}
+}
+
+func (t *progressTracker) addItemsets(n int) {
+	if t != nil {
+		t.itemsets.Add(int64(n))
+	}
+}
+
+func (t *progressTracker) addRules(n int) {
+	if t != nil {
+		t.rules.Add(int64(n))
+	}
+}
+
+func (t *progressTracker) setTreeNodes(n int) {
+	if t != nil {
+		t.treeNodes.Store(int64(n))
+	}
+}
+
+// finish stops periodic reports, and makes the phase's last report. Calls
+// after the first have no effect.
+func (t *progressTracker) finish() {
+	if t == nil {
+		return
+	}
+	t.finished.Do(func() {
+		close(t.stop)
+		<-t.stopped
+		t.reporter.Report(t.progress(true))
+	})
+}
+
+// progressReader counts the bytes read from r as progress.
+type progressReader struct {
+	r        io.Reader
+	progress *progressTracker
+}
+
+func (pr progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.progress.add(int64(n))
+	return n, err
+}
+
+// fileSizes returns the total size of the files at paths, ignoring files
+// which can't be read.
+func fileSizes(paths ...string) int64 {
+	size := int64(0)
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
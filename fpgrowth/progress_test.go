@@ -0,0 +1,70 @@
+package fpgrowth
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	defer func(interval time.Duration) { progressInterval = interval }(progressInterval)
+	progressInterval = time.Millisecond
+	defer func(size int64) { minChunkSize = size }(minChunkSize)
+	minChunkSize = 64
+
+	path := writeTestCsv(t, randomTransactions(2000, 10)...)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := make(map[Phase]Progress)
+	reporter := ProgressFunc(func(p Progress) {
+		if previous, found := last[p.Phase]; found {
+			if previous.Done {
+				t.Error("Report after phase ", p.Phase, " finished")
+			}
+			if p.Processed < previous.Processed {
+				t.Error("Progress of ", p.Phase, " went backwards")
+			}
+		}
+		last[p.Phase] = p
+	})
+	ctx, err := Init(path, WithProgress(reporter), WithParallelism(4), WithDensityThreshold(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := ctx.GenerateItemsets(0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := ctx.GenerateRules(itemsets, 0.5, 1)
+
+	numItems := len(frequentItems(&ctx, minCountFor(0.01, ctx.numTransactions)))
+	expected := map[Phase]Progress{
+		PhaseCounting:     {Processed: info.Size(), Total: info.Size()},
+		PhaseBuildingTree: {Processed: info.Size(), Total: info.Size()},
+		PhaseMining: {
+			Processed: int64(numItems),
+			Total:     int64(numItems),
+			Itemsets:  int64(len(itemsets)),
+		},
+		PhaseRules: {
+			Processed: int64(len(itemsets)),
+			Total:     int64(len(itemsets)),
+			Rules:     int64(len(rules)),
+		},
+	}
+	for phase, e := range expected {
+		p := last[phase]
+		if !p.Done || p.Processed != e.Processed || p.Total != e.Total ||
+			p.Itemsets != e.Itemsets || p.Rules != e.Rules {
+			t.Error("Expected final progress of ", phase, " to be ", e, ", got ", p)
+		}
+		if p.Fraction() != 1 {
+			t.Error("Expected ", phase, " to be complete, got ", p.Fraction())
+		}
+	}
+	if last[PhaseBuildingTree].TreeNodes == 0 {
+		t.Error("Expected the number of tree nodes to be reported")
+	}
+}
@@ -1,9 +1,9 @@
 package fpgrowth
 
 import (
-	"log"
+	"context"
 	"sort"
-	"time"
+	"sync"
 )
 
 // Rule represents an antecedent implies consequent rule, and stores its
@@ -36,79 +36,20 @@ func NewRule(
 	}
 }
 
-type itemsetWithSupport struct {
-	itemset []Item
-	support float64
-}
-
-func (isl itemsetSupportLookup) Len() int {
-	return len(isl.itemsets)
-}
-
-func (isl *itemsetSupportLookup) Swap(i, j int) {
-	isl.itemsets[i], isl.itemsets[j] = isl.itemsets[j], isl.itemsets[i]
-}
-
-func (isl *itemsetSupportLookup) Less(i, j int) bool {
-	return itemSliceLess(isl.itemsets[i].itemset, isl.itemsets[j].itemset)
-}
-
-type itemsetSupportLookup struct {
-	itemsets []itemsetWithSupport
-}
-
-func newItemsetSupportLookup() *itemsetSupportLookup {
-	return &itemsetSupportLookup{
-		itemsets: make([]itemsetWithSupport, 0),
-	}
-}
-
-func (isl *itemsetSupportLookup) insert(itemset []Item, support float64) {
-	isl.itemsets = append(
-		isl.itemsets,
-		itemsetWithSupport{itemset: itemset, support: support},
-	)
-}
-
-func (isl *itemsetSupportLookup) sort() {
-	sort.Sort(isl)
-}
-
-func (isl *itemsetSupportLookup) lookup(itemset []Item) float64 {
-	idx := sort.Search(len(isl.itemsets), func(idx int) bool {
-		return !itemSliceLess(isl.itemsets[idx].itemset, itemset)
-	})
-	if !itemSliceEquals(isl.itemsets[idx].itemset, itemset) {
-		panic("Failed to retrieve itemset support")
-	}
-	return isl.itemsets[idx].support
-}
-
-func createSupportLookup(
-	itemsets []ItemsetWithCount,
-	numTransactions int,
-) *itemsetSupportLookup {
-	isl := newItemsetSupportLookup()
-	f := float64(numTransactions)
-	for _, is := range itemsets {
-		isl.insert(is.Itemset, float64(is.Count)/f)
-	}
-	isl.sort()
-
-	return isl
-}
-
 func makeStats(
 	a []Item,
 	c []Item,
 	acSup float64,
-	supportLookup *itemsetSupportLookup,
-) (float64, float64) {
-	aSup := supportLookup.lookup(a)
+	supportLookup supportLookup,
+) (float64, float64, bool) {
+	aSup, aFound := supportLookup.lookup(a)
+	cSup, cFound := supportLookup.lookup(c)
+	if !aFound || !cFound {
+		return 0, 0, false
+	}
 	confidence := acSup / aSup
-	cSup := supportLookup.lookup(c)
 	lift := acSup / (aSup * cSup)
-	return confidence, lift
+	return confidence, lift, true
 }
 
 func itemSliceLess(a, b []Item) bool {
@@ -158,127 +99,185 @@ func countRules(rules [][]Rule) int {
 	return n
 }
 
+// rulesBlockSize is the number of itemsets in each job of generateRules.
+const rulesBlockSize = 1000
+
 func generateRules(
 	itemsets []ItemsetWithCount,
 	numTransactions int,
 	minConfidence float64,
 	minLift float64,
+	parallelism int,
 ) [][]Rule {
-	// Output rules are stored in a slice of slices. As we generate rules, we
-	// store them in a slice with capacity `chunkSize`. When the slice fills up,
-	// we append it to the output set. If we instead stuck all the rules in a
-	// single slice, we'd need to resize the slice as we append more rules, which
-	// is slow when we have a lot of rules in the slice.
-	output := make([][]Rule, 0)
-	const chunkSize int = 10000
-	rules := make([]Rule, 0, chunkSize)
-	itemsetSupport := createSupportLookup(itemsets, numTransactions)
+	rules, _ := generateRulesContext(
+		context.Background(),
+		itemsets,
+		numTransactions,
+		minConfidence,
+		minLift,
+		parallelism,
+		nil,
+	)
+	return rules
+}
 
-	lastFeedback := time.Now()
+// generateRulesContext is generateRules, but stops and returns c.Err() if c
+// is cancelled.
+func generateRulesContext(
+	c context.Context,
+	itemsets []ItemsetWithCount,
+	numTransactions int,
+	minConfidence float64,
+	minLift float64,
+	parallelism int,
+	progress *progressTracker,
+) ([][]Rule, error) {
+	// The itemsets are divided into blocks, and a pool of workers generate
+	// each block's rules into their own slice. The slices are output in block
+	// order, so the rules are in the same order regardless of parallelism.
+	// This also avoids expensive resizes of a single slice as we append a lot
+	// of rules to it.
+	itemsetSupport := createSupportLookup(itemsets, numTransactions)
+	numBlocks := (len(itemsets) + rulesBlockSize - 1) / rulesBlockSize
+	blocks := make([][]Rule, numBlocks)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(1, parallelism); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for block := range jobs {
+				start := block * rulesBlockSize
+				end := min(start+rulesBlockSize, len(itemsets))
+				rules := make([]Rule, 0)
+				for _, itemset := range itemsets[start:end] {
+					if cancelled(c) {
+						break
+					}
+					n := len(rules)
+					rules = appendItemsetRules(
+						rules,
+						itemset,
+						numTransactions,
+						minConfidence,
+						minLift,
+						itemsetSupport,
+					)
+					progress.add(1)
+					progress.addRules(len(rules) - n)
+				}
+				blocks[block] = rules
+			}
+		}()
+	}
+send:
+	for block := 0; block < numBlocks; block++ {
+		select {
+		case jobs <- block:
+		case <-c.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
 
-	for index, itemset := range itemsets {
-		support := float64(itemset.Count) / float64(numTransactions)
-		if time.Since(lastFeedback).Seconds() > 20 {
-			lastFeedback = time.Now()
-			percentComplete := int(
-				float64(index)/float64(countRules(output)+len(rules))*100 + 0.5,
-			)
-			log.Printf(
-				"Progress: %d of %d itemsets processed (%d%%), generated %d rules so far",
-				index,
-				len(itemsets),
-				percentComplete,
-				len(rules),
-			)
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	output := make([][]Rule, 0, numBlocks)
+	for _, rules := range blocks {
+		if len(rules) > 0 {
+			output = append(output, rules)
 		}
-		if len(itemset.Itemset) < 2 {
+	}
+	return output, nil
+}
+
+// appendItemsetRules appends the rules generated from itemset to rules.
+func appendItemsetRules(
+	rules []Rule,
+	itemset ItemsetWithCount,
+	numTransactions int,
+	minConfidence float64,
+	minLift float64,
+	itemsetSupport supportLookup,
+) []Rule {
+	if len(itemset.Itemset) < 2 {
+		return rules
+	}
+	support := float64(itemset.Count) / float64(numTransactions)
+	// First generation is all possible rules with consequents of size 1.
+	candidates := make([][]Item, 0)
+	for _, item := range itemset.Itemset {
+		consequent := []Item{item}
+		antecedent := setMinus(itemset.Itemset, consequent)
+		confidence, lift, found := makeStats(
+			antecedent,
+			consequent,
+			support,
+			itemsetSupport,
+		)
+		if !found || confidence < minConfidence {
 			continue
 		}
-		// First generation is all possible rules with consequents of size 1.
-		candidates := make([][]Item, 0)
-		for _, item := range itemset.Itemset {
-			consequent := []Item{item}
-			antecedent := setMinus(itemset.Itemset, consequent)
-			confidence, lift := makeStats(
-				antecedent,
-				consequent,
-				support,
-				itemsetSupport,
+		if lift >= minLift {
+			rules = append(
+				rules,
+				NewRule(antecedent, consequent, support, confidence, lift),
 			)
-			if confidence < minConfidence {
-				continue
-			}
-			if lift >= minLift {
-				rules = append(
-					rules,
-					NewRule(antecedent, consequent, support, confidence, lift),
-				)
-				if len(rules) == chunkSize {
-					output = append(output, rules)
-					rules = make([]Rule, 0, chunkSize)
-				}
-			}
-			candidates = append(candidates, consequent)
 		}
-		// Note: candidates should be sorted here.
+		candidates = append(candidates, consequent)
+	}
+	// Note: candidates should be sorted here.
 
-		// Create subsequent generations by merging consequents which have size-1 items
-		// in common in the consequent.
-		k := len(itemset.Itemset) // size of frequent itemset
-		for len(candidates) > 0 && len(candidates[0])+1 < k {
-			nextGen := make([][]Item, 0)
-			for idx1, c1 := range candidates {
-				m := len(c1) // size of consequent.
-				for idx2 := idx1 + 1; idx2 < len(candidates); idx2++ {
-					c2 := candidates[idx2]
-					if prefixMatchLen(c1, c2) != m-1 {
-						// The candidates list contains only items of the same length.
-						// The candidates list is sorted, and each candidate is sorted.
-						// We're trying to merge two consequents which have m-1 items in
-						// common. So we can stop searching for c2 once our prefix no
-						// longer matches m-1 items, as since the list is sorted, we can't
-						// find any more matches after that.
-						break
-					}
+	// Create subsequent generations by merging consequents which have size-1 items
+	// in common in the consequent.
+	k := len(itemset.Itemset) // size of frequent itemset
+	for len(candidates) > 0 && len(candidates[0])+1 < k {
+		nextGen := make([][]Item, 0)
+		for idx1, c1 := range candidates {
+			m := len(c1) // size of consequent.
+			for idx2 := idx1 + 1; idx2 < len(candidates); idx2++ {
+				c2 := candidates[idx2]
+				if prefixMatchLen(c1, c2) != m-1 {
+					// The candidates list contains only items of the same length.
+					// The candidates list is sorted, and each candidate is sorted.
+					// We're trying to merge two consequents which have m-1 items in
+					// common. So we can stop searching for c2 once our prefix no
+					// longer matches m-1 items, as since the list is sorted, we can't
+					// find any more matches after that.
+					break
+				}
 
-					consequent := union(c1, candidates[idx2])
-					antecedent := setMinus(itemset.Itemset, consequent)
+				consequent := union(c1, candidates[idx2])
+				antecedent := setMinus(itemset.Itemset, consequent)
 
-					confidence, lift := makeStats(
-						antecedent,
-						consequent,
-						support,
-						itemsetSupport,
+				confidence, lift, found := makeStats(
+					antecedent,
+					consequent,
+					support,
+					itemsetSupport,
+				)
+				if !found || confidence < minConfidence {
+					continue
+				}
+				nextGen = append(nextGen, consequent)
+				if lift >= minLift {
+					rules = append(
+						rules,
+						NewRule(
+							antecedent,
+							consequent,
+							support,
+							confidence,
+							lift,
+						),
 					)
-					if confidence < minConfidence {
-						continue
-					}
-					nextGen = append(nextGen, consequent)
-					if lift >= minLift {
-						rules = append(
-							rules,
-							NewRule(
-								antecedent,
-								consequent,
-								support,
-								confidence,
-								lift,
-							),
-						)
-						if len(rules) == chunkSize {
-							output = append(output, rules)
-							rules = make([]Rule, 0, chunkSize)
-						}
-					}
 				}
 			}
-			candidates = nextGen
-			sortCandidates(candidates)
 		}
+		candidates = nextGen
+		sortCandidates(candidates)
 	}
-
-	if len(rules) > 0 {
-		output = append(output, rules)
-	}
-	return output
+	return rules
 }
@@ -3,6 +3,7 @@ package fpgrowth
 import (
 	"log"
 	"math"
+	"reflect"
 	"testing"
 )
 
@@ -128,7 +129,7 @@ func TestGenerateRules(t *testing.T) {
 		{[]Item{11, 148}, []Item{6, 218}, 0.050, 0.894, 11.398},
 	}
 
-	rules := generateRules(itemsets, 990002, 0.05, 1.5)
+	rules := generateRules(itemsets, 990002, 0.05, 1.5, 4)
 	log.Printf("Generated %d rules", len(rules))
 	for _, rule := range rules {
 		log.Print(rule)
@@ -154,3 +155,38 @@ func TestGenerateRules(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateRulesMissingSubsets(t *testing.T) {
+	// Itemsets that aren't downward closed, such as ones loaded from a file
+	// or filtered by the caller, only generate rules whose subsets are known.
+	itemsets := []ItemsetWithCount{
+		{[]Item{1}, 6},
+		{[]Item{2}, 5},
+		{[]Item{1, 2, 3}, 4},
+		{[]Item{1, 2}, 5},
+	}
+	rules := flatten(generateRules(itemsets, 10, 0, 0, 1))
+	if len(rules) != 2 {
+		t.Error("Expected rules 1 => 2 and 2 => 1, got ", rules)
+	}
+}
+
+func TestGenerateRulesParallel(t *testing.T) {
+	transactions := zipfTransactions(2000, 60, 4)
+	tree := newTree()
+	for _, transaction := range transactions {
+		tree.Insert(transaction, 1)
+	}
+	itemsets := fpGrowth(tree, make([]Item, 0), 3)
+	if len(itemsets) <= rulesBlockSize {
+		t.Fatal("Expected several blocks of itemsets, got ", len(itemsets))
+	}
+	expected := flatten(generateRules(itemsets, len(transactions), 0.1, 1, 1))
+	for _, parallelism := range []int{0, 2, 7} {
+		observed := flatten(generateRules(itemsets, len(transactions), 0.1, 1, parallelism))
+		if !reflect.DeepEqual(observed, expected) {
+			t.Error("Rules generated with parallelism ", parallelism,
+				" differ from sequential rules")
+		}
+	}
+}
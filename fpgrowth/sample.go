@@ -0,0 +1,233 @@
+package fpgrowth
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+type sampledTransaction struct {
+	items []Item
+	count int
+}
+
+// reservoir maintains a uniform random sample of a stream of transactions.
+type reservoir struct {
+	size         int
+	seen         int
+	rng          *rand.Rand
+	transactions []sampledTransaction
+}
+
+func newReservoir(size int, seed int64) *reservoir {
+	return &reservoir{
+		size:         size,
+		rng:          rand.New(rand.NewSource(seed)),
+		transactions: make([]sampledTransaction, 0, size),
+	}
+}
+
+func (r *reservoir) add(items []Item, count int) {
+	r.seen++
+	if len(r.transactions) < r.size {
+		r.transactions = append(r.transactions, sampledTransaction{items, count})
+		return
+	}
+	if idx := r.rng.Intn(r.seen); idx < r.size {
+		r.transactions[idx] = sampledTransaction{items, count}
+	}
+}
+
+// SampleResult stores the itemsets generated by GenerateItemsetsFromSample().
+type SampleResult struct {
+	// Itemsets are frequent itemsets, with their exact counts.
+	Itemsets GeneratedItemsets
+	// Complete is true if Itemsets is guaranteed to contain every frequent
+	// itemset. If it's false, more passes were needed to verify the result.
+	Complete bool
+	// Passes is the number of full passes made over the dataset, excluding
+	// the first pass made by Init().
+	Passes int
+}
+
+// GenerateItemsetsFromSample generates frequent itemsets with support above
+// minSupport using Toivonen's sampling algorithm. The sample collected by
+// passing WithSample() to Init() is mined with the lowered threshold
+// sampleSupport. The itemsets found, and their negative border, the minimal
+// itemsets that weren't found but whose subsets all were, are then counted
+// exactly in one pass over the dataset. If no itemset in the negative border
+// is frequent, the result is guaranteed complete. Otherwise the frequent
+// itemsets' negative border is counted in a further pass, repeating until the
+// result is complete or maxPasses passes have been made.
+//
+// This avoids building an FP-tree of the full dataset, so uses much less
+// memory than GenerateItemsets() on large datasets.
+func (ctx Context) GenerateItemsetsFromSample(
+	minSupport float64,
+	sampleSupport float64,
+	maxPasses int,
+) (SampleResult, error) {
+	return ctx.GenerateItemsetsFromSampleContext(context.Background(), minSupport, sampleSupport, maxPasses)
+}
+
+// GenerateItemsetsFromSampleContext is like GenerateItemsetsFromSample, but
+// stops and returns c.Err() soon after c is cancelled.
+func (ctx Context) GenerateItemsetsFromSampleContext(
+	c context.Context,
+	minSupport float64,
+	sampleSupport float64,
+	maxPasses int,
+) (SampleResult, error) {
+	if ctx.sample == nil {
+		return SampleResult{}, errors.New("Context was created without WithSample()")
+	}
+	minCount := minCountFor(minSupport, ctx.numTransactions)
+
+	// Item frequencies are known exactly from the first pass, so only
+	// frequent items need to be considered.
+	frequentItem := func(item Item) bool {
+		return ctx.frequency.get(item) >= minCount
+	}
+	sampleTree := newTree()
+	sampleTransactions := 0
+	for _, t := range ctx.sample.transactions {
+		sampleTransactions += t.count
+		items := make([]Item, 0, len(t.items))
+		for _, item := range t.items {
+			if frequentItem(item) {
+				items = append(items, item)
+			}
+		}
+		sampleTree.Insert(items, t.count)
+	}
+	sampleMinCount := int(math.Ceil(sampleSupport * float64(sampleTransactions)))
+
+	// counts stores the exact count of every itemset counted so far.
+	counts := newItemsetTable(0)
+	candidates := make([][]Item, 0)
+	for _, item := range frequentItems(&ctx, minCount) {
+		itemset := []Item{item}
+		counts.set(itemset, ctx.frequency.get(item))
+		candidates = append(candidates, itemset)
+	}
+	sampleItemsets, err := fpGrowthContext(c, sampleTree, make([]Item, 0), max(1, sampleMinCount), nil, nil)
+	if err != nil {
+		return SampleResult{}, err
+	}
+	for _, iwc := range sampleItemsets {
+		if len(iwc.Itemset) > 1 {
+			candidates = append(candidates, iwc.Itemset)
+		}
+	}
+	// Counting the candidates and their border in the first pass only needs
+	// the candidates to be downward closed, which the sample's itemsets are.
+	candidates = append(candidates, negativeBorder(candidates)...)
+
+	result := SampleResult{}
+	for {
+		if err := c.Err(); err != nil {
+			return SampleResult{}, err
+		}
+		uncounted := make([][]Item, 0)
+		for _, candidate := range candidates {
+			if !counts.contains(candidate) {
+				uncounted = append(uncounted, candidate)
+			}
+		}
+		if len(uncounted) > 0 {
+			if result.Passes == maxPasses {
+				break
+			}
+			passCounts, err := ctx.countItemsets(c, uncounted)
+			if err != nil {
+				return SampleResult{}, err
+			}
+			result.Passes++
+			for i, candidate := range uncounted {
+				counts.set(candidate, passCounts[i])
+			}
+		}
+
+		frequent := make([][]Item, 0)
+		result.Itemsets = make(GeneratedItemsets, 0)
+		for _, candidate := range candidates {
+			count, _ := counts.get(candidate)
+			if count >= minCount {
+				frequent = append(frequent, candidate)
+				result.Itemsets = append(result.Itemsets, ItemsetWithCount{candidate, count})
+			}
+		}
+		border := negativeBorder(frequent)
+		frequentBorder := false
+		for _, b := range border {
+			count, found := counts.get(b)
+			if !found || count >= minCount {
+				frequentBorder = true
+				break
+			}
+		}
+		if !frequentBorder {
+			result.Complete = true
+			break
+		}
+		candidates = append(frequent, border...)
+	}
+	return result, nil
+}
+
+// negativeBorder returns the itemsets which aren't in itemsets, but all of
+// whose immediate subsets are, excluding single items. Each of itemsets must
+// be sorted, and the collection must be downward closed.
+func negativeBorder(itemsets [][]Item) [][]Item {
+	known := newItemsetTable(len(itemsets))
+	levels := make(map[int][][]Item)
+	for _, itemset := range itemsets {
+		known.set(itemset, 0)
+		levels[len(itemset)] = append(levels[len(itemset)], itemset)
+	}
+	border := make([][]Item, 0)
+	for _, level := range levels {
+		for _, c := range aprioriGen(level, known) {
+			if !known.contains(c) {
+				border = append(border, c)
+			}
+		}
+	}
+	sort.SliceStable(border, sliceOfItemSliceLessThan(border))
+	return border
+}
+
+// aprioriGen returns the itemsets of size k+1 formed by joining pairs of
+// itemsets in level, which all have size k and are sorted, and that have all
+// their immediate subsets in known.
+func aprioriGen(level [][]Item, known *itemsetTable) [][]Item {
+	candidates := make([][]Item, 0)
+	if len(level) == 0 {
+		return candidates
+	}
+	k := len(level[0])
+	sortCandidates(level)
+	for i, a := range level {
+		for _, b := range level[i+1:] {
+			if prefixMatchLen(a[:k-1], b[:k-1]) != k-1 {
+				// Sorted, so no more itemsets share a's prefix.
+				break
+			}
+			c := appendSorted(a, b[k-1])
+			allSubsetsKnown := true
+			for _, item := range c {
+				subset, _ := without(c, item)
+				if !known.contains(subset) {
+					allSubsetsKnown = false
+					break
+				}
+			}
+			if allSubsetsKnown {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,76 @@
+package fpgrowth
+
+import (
+	"testing"
+)
+
+func TestNegativeBorder(t *testing.T) {
+	itemsets := [][]Item{{1}, {2}, {3}, {4}, {1, 2}, {1, 3}, {2, 3}, {3, 4}}
+	expected := [][]Item{{1, 4}, {2, 4}, {1, 2, 3}}
+	border := negativeBorder(itemsets)
+	if len(border) != len(expected) {
+		t.Fatal("Expected border ", expected, " got ", border)
+	}
+	for i := range expected {
+		if !itemSliceEquals(border[i], expected[i]) {
+			t.Error("Expected border ", expected, " got ", border)
+		}
+	}
+}
+
+func TestGenerateItemsetsFromSample(t *testing.T) {
+	lines := randomTransactions(2000, 4)
+	path := writeTestCsv(t, lines...)
+	exactCtx, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := Init(path, WithSample(300, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctx.sample.transactions) != 300 || ctx.sample.seen != 2000 {
+		t.Error("Unexpected sample size ", len(ctx.sample.transactions))
+	}
+
+	const minSupport = 0.15
+	exact, err := exactCtx.GenerateItemsets(minSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := itemsetCounts(exactCtx, exact)
+
+	// A lowered threshold should find everything in one pass, and a raised
+	// one should fail the border check and need further passes.
+	for _, sampleSupport := range []float64{0.1, 0.5} {
+		result, err := ctx.GenerateItemsetsFromSample(minSupport, sampleSupport, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Complete {
+			t.Error("sampleSupport=", sampleSupport, ": expected complete result")
+		}
+		if sampleSupport < minSupport && result.Passes != 1 {
+			t.Error("Expected a single pass, made ", result.Passes)
+		}
+		if sampleSupport > minSupport && result.Passes < 2 {
+			t.Error("Expected border check to fail, made ", result.Passes, " passes")
+		}
+		if o := itemsetCounts(ctx, result.Itemsets); !equalCounts(o, e) {
+			t.Error("sampleSupport=", sampleSupport, ": itemsets ", o,
+				" don't match exact ", e)
+		}
+	}
+
+	result, err := ctx.GenerateItemsetsFromSample(minSupport, 0.9, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Complete || result.Passes != 1 {
+		t.Error("Expected incomplete result after one pass, got ", result.Complete, result.Passes)
+	}
+
+	if _, err := exactCtx.GenerateItemsetsFromSample(minSupport, 0.1, 1); err == nil {
+		t.Error("Expected error sampling without WithSample()")
+	}
+}
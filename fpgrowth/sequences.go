@@ -0,0 +1,402 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SequentialPattern is a sequence of itemsets, along with the number of
+// input sequences which contain it. Each itemset in the sequence is sorted.
+type SequentialPattern struct {
+	Sequence [][]Item
+	Count    int
+}
+
+// SequentialRule represents a rule of the form "sequence Antecedent is later
+// followed by sequence Consequent", and stores its support, confidence, and
+// lift.
+type SequentialRule struct {
+	Antecedent [][]Item
+	Consequent [][]Item
+	Support    float64
+	Confidence float64
+	Lift       float64
+}
+
+// SequenceConstraints restrict which occurrences of a pattern in an input
+// sequence count towards its support. Distances are measured in positions in
+// the input sequence, so consecutive itemsets have a gap of 1. Zero values
+// mean unconstrained.
+type SequenceConstraints struct {
+	// MaxGap is the maximum distance between the positions of consecutive
+	// itemsets of the pattern.
+	MaxGap int
+	// MaxWindow is the maximum distance between the positions of the first
+	// and last itemsets of the pattern.
+	MaxWindow int
+}
+
+// SequenceContext stores context for a sequential pattern analysis.
+type SequenceContext struct {
+	inputCsvPath string
+	itemizer     Itemizer
+	// frequency counts the number of sequences containing each item.
+	frequency    itemCount
+	numSequences int
+}
+
+// InitSequences creates a SequenceContext. Each line of the input file is one
+// customer's sequence of itemsets in order of occurrence. Itemsets are
+// separated by semicolons, and items within an itemset by commas, for
+// example "camera,bag;lens;tripod". Performs a first pass on the dataset,
+// counting the number of sequences each item occurs in.
+func InitSequences(inputCsvPath string) (SequenceContext, error) {
+	ctx := SequenceContext{
+		inputCsvPath: inputCsvPath,
+		itemizer:     newItemizer(),
+		frequency:    makeCounts(),
+	}
+	err := ctx.forEachSequence(func(sequence [][]Item) {
+		ctx.numSequences++
+		seen := make(map[Item]bool)
+		for _, itemset := range sequence {
+			for _, item := range itemset {
+				if !seen[item] {
+					seen[item] = true
+					ctx.frequency.increment(item, 1)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return SequenceContext{}, err
+	}
+	return ctx, nil
+}
+
+func (ctx *SequenceContext) parseSequence(line string) [][]Item {
+	sequence := make([][]Item, 0)
+	for _, element := range strings.Split(line, ";") {
+		itemset := make([]Item, 0)
+		ctx.itemizer.forEachItem(strings.Split(element, ","), func(item Item) {
+			idx := sort.Search(len(itemset), func(i int) bool {
+				return itemset[i] >= item
+			})
+			if idx == len(itemset) || itemset[idx] != item {
+				itemset = appendSorted(itemset, item)
+			}
+		})
+		if len(itemset) > 0 {
+			sequence = append(sequence, itemset)
+		}
+	}
+	return sequence
+}
+
+func (ctx *SequenceContext) forEachSequence(fn func([][]Item)) error {
+	file, err := os.Open(ctx.inputCsvPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fn(ctx.parseSequence(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// sequenceOccurrence records the positions in an input sequence of the first
+// and last itemsets of an occurrence of a pattern.
+type sequenceOccurrence struct {
+	first int
+	last  int
+}
+
+// sequenceProjection stores all the occurrences of a pattern in one input
+// sequence, sorted by last position. Only the latest first position is kept
+// for each last position, as that's the occurrence most likely to satisfy
+// a window constraint when extended.
+type sequenceProjection struct {
+	sid         int
+	occurrences []sequenceOccurrence
+}
+
+func newSequenceProjection(sid int, occurrences []sequenceOccurrence) sequenceProjection {
+	sort.Slice(occurrences, func(i, j int) bool {
+		if occurrences[i].last != occurrences[j].last {
+			return occurrences[i].last < occurrences[j].last
+		}
+		return occurrences[i].first > occurrences[j].first
+	})
+	n := 0
+	for i, o := range occurrences {
+		if i == 0 || o.last != occurrences[n-1].last {
+			occurrences[n] = o
+			n++
+		}
+	}
+	return sequenceProjection{sid: sid, occurrences: occurrences[:n]}
+}
+
+type sequenceMiner struct {
+	sequences   [][][]Item
+	minCount    int
+	constraints SequenceConstraints
+	patterns    []SequentialPattern
+}
+
+// GenerateSequentialPatterns generates the sequential patterns which occur in
+// at least minSupport of the input sequences, subject to constraints. Uses
+// PrefixSpan, projecting the database on every occurrence of each pattern so
+// that gap and window constraints are respected.
+func (ctx SequenceContext) GenerateSequentialPatterns(
+	minSupport float64,
+	constraints SequenceConstraints,
+) ([]SequentialPattern, error) {
+	minCount := max(1, int(math.Ceil(minSupport*float64(ctx.numSequences))))
+
+	// Load the sequences into memory, dropping infrequent items.
+	sequences := make([][][]Item, 0, ctx.numSequences)
+	err := ctx.forEachSequence(func(sequence [][]Item) {
+		filtered := make([][]Item, 0, len(sequence))
+		for _, itemset := range sequence {
+			frequent := make([]Item, 0, len(itemset))
+			for _, item := range itemset {
+				if ctx.frequency.get(item) >= minCount {
+					frequent = append(frequent, item)
+				}
+			}
+			// Keep empty itemsets so that gaps are measured in the
+			// original positions.
+			filtered = append(filtered, frequent)
+		}
+		sequences = append(sequences, filtered)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	miner := sequenceMiner{
+		sequences:   sequences,
+		minCount:    minCount,
+		constraints: constraints,
+		patterns:    make([]SequentialPattern, 0),
+	}
+	initial := make(map[Item][]sequenceProjection)
+	for sid, sequence := range sequences {
+		occurrences := make(map[Item][]sequenceOccurrence)
+		for pos, itemset := range sequence {
+			for _, item := range itemset {
+				occurrences[item] = append(
+					occurrences[item],
+					sequenceOccurrence{pos, pos},
+				)
+			}
+		}
+		for item, occ := range occurrences {
+			initial[item] = append(initial[item], newSequenceProjection(sid, occ))
+		}
+	}
+	for _, item := range sortedKeys(initial) {
+		projected := initial[item]
+		if len(projected) < minCount {
+			continue
+		}
+		miner.grow([][]Item{{item}}, projected)
+	}
+	return miner.patterns, nil
+}
+
+func sortedKeys(m map[Item][]sequenceProjection) []Item {
+	keys := make([]Item, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func (m *sequenceMiner) grow(pattern [][]Item, projected []sequenceProjection) {
+	m.patterns = append(m.patterns, SequentialPattern{
+		Sequence: pattern,
+		Count:    len(projected),
+	})
+
+	lastItemset := pattern[len(pattern)-1]
+	lastItem := lastItemset[len(lastItemset)-1]
+
+	// Extensions which add an item to the last itemset of the pattern, and
+	// extensions which append a new itemset to the pattern.
+	itemsetExt := make(map[Item][]sequenceProjection)
+	sequenceExt := make(map[Item][]sequenceProjection)
+	for _, p := range projected {
+		sequence := m.sequences[p.sid]
+		iext := make(map[Item][]sequenceOccurrence)
+		sext := make(map[Item][]sequenceOccurrence)
+		for _, o := range p.occurrences {
+			for _, item := range sequence[o.last] {
+				if item > lastItem {
+					iext[item] = append(iext[item], o)
+				}
+			}
+			end := len(sequence) - 1
+			if m.constraints.MaxGap > 0 {
+				end = min(end, o.last+m.constraints.MaxGap)
+			}
+			if m.constraints.MaxWindow > 0 {
+				end = min(end, o.first+m.constraints.MaxWindow)
+			}
+			for pos := o.last + 1; pos <= end; pos++ {
+				for _, item := range sequence[pos] {
+					sext[item] = append(sext[item], sequenceOccurrence{o.first, pos})
+				}
+			}
+		}
+		for item, occ := range iext {
+			itemsetExt[item] = append(itemsetExt[item], newSequenceProjection(p.sid, occ))
+		}
+		for item, occ := range sext {
+			sequenceExt[item] = append(sequenceExt[item], newSequenceProjection(p.sid, occ))
+		}
+	}
+
+	for _, item := range sortedKeys(itemsetExt) {
+		if len(itemsetExt[item]) < m.minCount {
+			continue
+		}
+		next := make([][]Item, len(pattern))
+		copy(next, pattern)
+		next[len(next)-1] = appendSorted(lastItemset, item)
+		m.grow(next, itemsetExt[item])
+	}
+	for _, item := range sortedKeys(sequenceExt) {
+		if len(sequenceExt[item]) < m.minCount {
+			continue
+		}
+		next := make([][]Item, len(pattern), len(pattern)+1)
+		copy(next, pattern)
+		m.grow(append(next, []Item{item}), sequenceExt[item])
+	}
+}
+
+func sequenceKey(sequence [][]Item) string {
+	var b strings.Builder
+	for _, itemset := range sequence {
+		for _, item := range itemset {
+			fmt.Fprintf(&b, "%d ", item)
+		}
+		b.WriteString(";")
+	}
+	return b.String()
+}
+
+// itemSliceKey returns a string uniquely identifying itemset, for use as a
+// map key.
+func itemSliceKey(itemset []Item) string {
+	return sequenceKey([][]Item{itemset})
+}
+
+// GenerateSequentialRules generates sequential rules with confidence/lift
+// above minConfidence/minLift. Each pattern is split into an antecedent prefix
+// and a consequent suffix at every itemset boundary.
+func (ctx SequenceContext) GenerateSequentialRules(
+	patterns []SequentialPattern,
+	minConfidence float64,
+	minLift float64,
+) []SequentialRule {
+	n := float64(ctx.numSequences)
+	support := make(map[string]float64, len(patterns))
+	for _, p := range patterns {
+		support[sequenceKey(p.Sequence)] = float64(p.Count) / n
+	}
+	rules := make([]SequentialRule, 0)
+	for _, p := range patterns {
+		acSup := float64(p.Count) / n
+		for k := 1; k < len(p.Sequence); k++ {
+			a, c := p.Sequence[:k], p.Sequence[k:]
+			aSup, aFound := support[sequenceKey(a)]
+			cSup, cFound := support[sequenceKey(c)]
+			if !aFound || !cFound {
+				continue
+			}
+			confidence := acSup / aSup
+			lift := confidence / cSup
+			if confidence < minConfidence || lift < minLift {
+				continue
+			}
+			rules = append(rules, SequentialRule{
+				Antecedent: a,
+				Consequent: c,
+				Support:    acSup,
+				Confidence: confidence,
+				Lift:       lift,
+			})
+		}
+	}
+	return rules
+}
+
+func writeSequence(w *bufio.Writer, itemizer *Itemizer, sequence [][]Item) {
+	for i, itemset := range sequence {
+		if i != 0 {
+			fmt.Fprint(w, " ")
+		}
+		fmt.Fprint(w, "(")
+		writeItemset(w, itemizer, itemset)
+		fmt.Fprint(w, ")")
+	}
+}
+
+// WriteSequentialPatterns writes sequential patterns to CSV file. Itemsets
+// in each sequence are enclosed in parentheses.
+func (ctx SequenceContext) WriteSequentialPatterns(
+	patterns []SequentialPattern,
+	filePath string,
+) error {
+	output, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Sequence,Support")
+	n := float64(ctx.numSequences)
+	for _, p := range patterns {
+		writeSequence(w, &ctx.itemizer, p.Sequence)
+		fmt.Fprintf(w, ",%f\n", float64(p.Count)/n)
+	}
+	return w.Flush()
+}
+
+// WriteSequentialRules writes sequential rules to CSV file.
+func (ctx SequenceContext) WriteSequentialRules(
+	outputPath string,
+	rules []SequentialRule,
+) error {
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Antecedent => Consequent,Confidence,Lift,Support")
+	for _, rule := range rules {
+		writeSequence(w, &ctx.itemizer, rule.Antecedent)
+		fmt.Fprint(w, " => ")
+		writeSequence(w, &ctx.itemizer, rule.Consequent)
+		fmt.Fprintf(
+			w,
+			",%f,%f,%f\n",
+			rule.Confidence,
+			rule.Lift,
+			rule.Support,
+		)
+	}
+	return w.Flush()
+}
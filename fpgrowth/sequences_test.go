@@ -0,0 +1,114 @@
+package fpgrowth
+
+import (
+	"math"
+	"testing"
+)
+
+func sequenceCounts(ctx SequenceContext, patterns []SequentialPattern) map[string]int {
+	counts := make(map[string]int)
+	for _, p := range patterns {
+		key := ""
+		for i, itemset := range p.Sequence {
+			if i != 0 {
+				key += ";"
+			}
+			for j, item := range itemset {
+				if j != 0 {
+					key += ","
+				}
+				key += ctx.itemizer.ToStr(item)
+			}
+		}
+		counts[key] = p.Count
+	}
+	return counts
+}
+
+func TestSequentialPatterns(t *testing.T) {
+	input := writeTestCsv(t,
+		"a;b;c",
+		"a;c",
+		"a,b;c",
+		"b;a",
+	)
+	ctx, err := InitSequences(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		constraints SequenceConstraints
+		expected    map[string]int
+	}{
+		{
+			SequenceConstraints{},
+			map[string]int{"a": 4, "b": 3, "c": 3, "a;c": 3, "b;c": 2},
+		},
+		{
+			SequenceConstraints{MaxGap: 1},
+			map[string]int{"a": 4, "b": 3, "c": 3, "a;c": 2, "b;c": 2},
+		},
+	}
+	for _, tc := range testCases {
+		patterns, err := ctx.GenerateSequentialPatterns(0.5, tc.constraints)
+		if err != nil {
+			t.Fatal(err)
+		}
+		observed := sequenceCounts(ctx, patterns)
+		if !equalCounts(observed, tc.expected) {
+			t.Error(tc.constraints, ": expected ", tc.expected, " got ", observed)
+		}
+	}
+
+	patterns, err := ctx.GenerateSequentialPatterns(0.25, SequenceConstraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := sequenceCounts(ctx, patterns)
+	for key, count := range map[string]int{"a,b": 1, "a;b;c": 1, "b;a": 1} {
+		if observed[key] != count {
+			t.Error("Expected ", key, " count ", count, " got ", observed[key])
+		}
+	}
+	patterns, err = ctx.GenerateSequentialPatterns(0.25, SequenceConstraints{MaxWindow: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := sequenceCounts(ctx, patterns)["a;b;c"]; found {
+		t.Error("a;b;c spans a window of 2, but was generated with MaxWindow 1")
+	}
+}
+
+func TestSequentialRules(t *testing.T) {
+	input := writeTestCsv(t,
+		"a;b;c",
+		"a;c",
+		"a,b;c",
+		"b;a",
+	)
+	ctx, err := InitSequences(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns, err := ctx.GenerateSequentialPatterns(0.5, SequenceConstraints{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := ctx.GenerateSequentialRules(patterns, 0, 0)
+	if len(rules) != 2 {
+		t.Fatal("Expected 2 rules, got ", rules)
+	}
+	expected := map[string][3]float64{
+		"a": {0.75, 0.75, 1},
+		"b": {0.5, 2.0 / 3.0, 0.5 / (0.75 * 0.75)},
+	}
+	for _, rule := range rules {
+		e := expected[ctx.itemizer.ToStr(rule.Antecedent[0][0])]
+		if math.Abs(rule.Support-e[0]) > 1e-9 ||
+			math.Abs(rule.Confidence-e[1]) > 1e-9 ||
+			math.Abs(rule.Lift-e[2]) > 1e-9 {
+			t.Error("Unexpected rule ", rule, " expected ", e)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// itemsetOverhead is the approximate number of bytes an ItemsetWithCount
+// uses in memory, excluding its items.
+const itemsetOverhead = 56
+
+// maxMergeRuns is the number of run files merged at once. Runs beyond this
+// are merged into larger runs first, so as not to open too many files.
+const maxMergeRuns = 64
+
+// spillIndexInterval is the number of itemsets between entries of the index
+// of a SpilledItemsets' file, and so the number in each block read by lookups.
+const spillIndexInterval = 128
+
+// spillCacheBlocks is the number of decoded blocks a SpilledItemsets caches.
+const spillCacheBlocks = 256
+
+// WithMemoryBudget limits the memory used to store the itemsets generated by
+// GenerateSpilledItemsets() to about bytes. Once the budget is exceeded, the
+// itemsets held in memory are sorted and written to a temporary run file in
+// a new directory in dir, or in os.TempDir() if dir is empty. The runs are
+// merged into a single sorted file, which rule generation looks supports up
+// in. The FP-tree itself isn't covered by the budget.
+func WithMemoryBudget(bytes int64, dir string) Option {
+	return func(o *options) {
+		o.memoryBudget = bytes
+		o.spillDir = dir
+	}
+}
+
+func itemsetSize(iwc ItemsetWithCount) int64 {
+	return itemsetOverhead + 8*int64(len(iwc.Itemset))
+}
+
+func compareItemsets(a, b []Item) int {
+	return slices.Compare(a, b)
+}
+
+func appendItemsetRecord(buf []byte, iwc ItemsetWithCount) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(iwc.Itemset)))
+	for _, item := range iwc.Itemset {
+		buf = binary.AppendUvarint(buf, uint64(item))
+	}
+	return binary.AppendUvarint(buf, uint64(iwc.Count))
+}
+
+// readItemsetRecord reads an itemset written by appendItemsetRecord. Returns
+// io.EOF if r is at its end.
+func readItemsetRecord(r io.ByteReader) (ItemsetWithCount, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ItemsetWithCount{}, err
+	}
+	itemset := make([]Item, n)
+	for i := range itemset {
+		item, err := binary.ReadUvarint(r)
+		if err != nil {
+			return ItemsetWithCount{}, unexpectedEOF(err)
+		}
+		itemset[i] = Item(item)
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ItemsetWithCount{}, unexpectedEOF(err)
+	}
+	return ItemsetWithCount{itemset, int(count)}, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// spiller buffers itemsets in memory, and writes them to sorted run files
+// whenever their size exceeds budget.
+type spiller struct {
+	logger *slog.Logger
+	budget int64
+	dir    string
+	// path is the directory in dir the runs are written to, created when the
+	// first run is written.
+	path   string
+	buffer []ItemsetWithCount
+	size   int64
+	runs   []string
+}
+
+func (s *spiller) add(itemsets []ItemsetWithCount) error {
+	for _, iwc := range itemsets {
+		s.buffer = append(s.buffer, iwc)
+		s.size += itemsetSize(iwc)
+		if s.budget > 0 && s.size > s.budget {
+			if err := s.spill(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *spiller) sortBuffer() {
+	slices.SortFunc(s.buffer, func(a, b ItemsetWithCount) int {
+		return compareItemsets(a.Itemset, b.Itemset)
+	})
+}
+
+// spill writes the buffered itemsets to a new run file.
+func (s *spiller) spill() error {
+	s.sortBuffer()
+	err := s.writeRun(func(yield func(ItemsetWithCount) error) error {
+		for _, iwc := range s.buffer {
+			if err := yield(iwc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.Debug(
+		"spilled itemsets",
+		"itemsets", len(s.buffer),
+		"bytes", s.size,
+		"run", s.runs[len(s.runs)-1],
+	)
+	s.buffer = nil
+	s.size = 0
+	return nil
+}
+
+// writeRun writes the itemsets produced by generate to a new run file.
+func (s *spiller) writeRun(
+	generate func(yield func(ItemsetWithCount) error) error,
+) error {
+	if s.path == "" {
+		path, err := os.MkdirTemp(s.dir, "arm-spill-")
+		if err != nil {
+			return err
+		}
+		s.path = path
+	}
+	file, err := os.CreateTemp(s.path, "run-")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, file.Name())
+	w := bufio.NewWriter(file)
+	buf := make([]byte, 0, 64)
+	err = generate(func(iwc ItemsetWithCount) error {
+		buf = appendItemsetRecord(buf[:0], iwc)
+		_, err := w.Write(buf)
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// merge calls fn with every itemset spilled to a run, and any still
+// buffered, in sorted order.
+func (s *spiller) merge(fn func(ItemsetWithCount) error) error {
+	if len(s.buffer) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	for len(s.runs) > maxMergeRuns {
+		runs := s.runs[:maxMergeRuns]
+		s.runs = s.runs[maxMergeRuns:]
+		err := s.writeRun(func(yield func(ItemsetWithCount) error) error {
+			return mergeRuns(runs, yield)
+		})
+		if err != nil {
+			return err
+		}
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}
+	return mergeRuns(s.runs, fn)
+}
+
+// remove deletes the spiller's run files.
+func (s *spiller) remove() error {
+	if s.path == "" {
+		return nil
+	}
+	return os.RemoveAll(s.path)
+}
+
+type runReader struct {
+	file *os.File
+	r    *bufio.Reader
+	head ItemsetWithCount
+}
+
+// runHeap orders runs by their next itemset.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	return compareItemsets(h[i].head.Itemset, h[j].head.Itemset) < 0
+}
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)   { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// mergeRuns calls fn with the itemsets of the sorted run files in paths, in
+// sorted order.
+func mergeRuns(paths []string, fn func(ItemsetWithCount) error) error {
+	h := make(runHeap, 0, len(paths))
+	defer func() {
+		for _, run := range h {
+			run.file.Close()
+		}
+	}()
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		run := &runReader{file: file, r: bufio.NewReader(file)}
+		run.head, err = readItemsetRecord(run.r)
+		if err == io.EOF {
+			file.Close()
+			continue
+		} else if err != nil {
+			file.Close()
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		h = append(h, run)
+	}
+	heap.Init(&h)
+	for len(h) > 0 {
+		run := h[0]
+		if err := fn(run.head); err != nil {
+			return err
+		}
+		var err error
+		run.head, err = readItemsetRecord(run.r)
+		if err == io.EOF {
+			run.file.Close()
+			heap.Pop(&h)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", run.file.Name(), err)
+		}
+		heap.Fix(&h, 0)
+	}
+	return nil
+}
+
+// spillIndexEntry records the offset of a block of a SpilledItemsets' file,
+// and the block's first itemset.
+type spillIndexEntry struct {
+	offset int64
+	first  []Item
+}
+
+// SpilledItemsets stores frequent itemsets generated within a memory budget
+// by GenerateSpilledItemsets(). If the itemsets fit within the budget they're
+// stored in memory, otherwise they're stored sorted in a temporary file, with
+// an index of every 128th itemset held in memory. Call Close() to remove the
+// temporary files. A SpilledItemsets isn't safe for concurrent use.
+type SpilledItemsets struct {
+	numTransactions int
+	len             int
+	spiller         *spiller
+
+	// itemsets and support store the itemsets if nothing was spilled.
+	itemsets []ItemsetWithCount
+	support  *itemsetSupportLookup
+
+	// Otherwise file stores the sorted itemsets, and index its blocks.
+	file   *os.File
+	size   int64
+	index  []spillIndexEntry
+	blocks map[int][]ItemsetWithCount
+	cached []int
+	// err is the first error reading a block, which lookup can't return.
+	err error
+}
+
+// Len returns the number of itemsets.
+func (s *SpilledItemsets) Len() int {
+	return s.len
+}
+
+// Spilled reports whether the itemsets exceeded the memory budget, and are
+// stored on disk.
+func (s *SpilledItemsets) Spilled() bool {
+	return s.file != nil
+}
+
+// ForEach calls fn with each itemset, in sorted order, stopping if fn
+// returns an error.
+func (s *SpilledItemsets) ForEach(fn func(ItemsetWithCount) error) error {
+	if s.file == nil {
+		for _, iwc := range s.itemsets {
+			if err := fn(iwc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	r := bufio.NewReader(io.NewSectionReader(s.file, 0, s.size))
+	for read := 0; ; read++ {
+		iwc, err := readItemsetRecord(r)
+		if err == io.EOF && read < s.len {
+			return io.ErrUnexpectedEOF
+		} else if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(iwc); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the temporary files storing the itemsets.
+func (s *SpilledItemsets) Close() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	return s.spiller.remove()
+}
+
+// lookup returns itemset's support, and whether it's stored. If reading the
+// file fails, itemset isn't found, and the error is stored in s.err.
+func (s *SpilledItemsets) lookup(itemset []Item) (float64, bool) {
+	if s.file == nil {
+		return s.support.lookup(itemset)
+	}
+	i := sort.Search(len(s.index), func(i int) bool {
+		return compareItemsets(s.index[i].first, itemset) > 0
+	}) - 1
+	if i < 0 {
+		return 0, false
+	}
+	block, err := s.block(i)
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return 0, false
+	}
+	j, found := slices.BinarySearchFunc(block, itemset, func(iwc ItemsetWithCount, itemset []Item) int {
+		return compareItemsets(iwc.Itemset, itemset)
+	})
+	if !found {
+		return 0, false
+	}
+	return float64(block[j].Count) / float64(s.numTransactions), true
+}
+
+// block returns the itemsets of the i'th block of the file.
+func (s *SpilledItemsets) block(i int) ([]ItemsetWithCount, error) {
+	if block, found := s.blocks[i]; found {
+		return block, nil
+	}
+	end := s.size
+	if i+1 < len(s.index) {
+		end = s.index[i+1].offset
+	}
+	buf := make([]byte, end-s.index[i].offset)
+	if _, err := s.file.ReadAt(buf, s.index[i].offset); err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(buf)
+	block := make([]ItemsetWithCount, 0, spillIndexInterval)
+	for r.Len() > 0 {
+		iwc, err := readItemsetRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		block = append(block, iwc)
+	}
+	if len(s.cached) == spillCacheBlocks {
+		delete(s.blocks, s.cached[0])
+		s.cached = s.cached[1:]
+	}
+	s.blocks[i] = block
+	s.cached = append(s.cached, i)
+	return block, nil
+}
+
+// GenerateSpilledItemsets generates frequent itemsets with support above
+// minSupport via FP-growth, keeping them within the memory budget passed to
+// Init() with WithMemoryBudget(). Without a budget, all the itemsets are kept
+// in memory. Stops and returns c.Err() if c is cancelled.
+func (ctx Context) GenerateSpilledItemsets(
+	c context.Context,
+	minSupport float64,
+) (*SpilledItemsets, error) {
+	s := &spiller{
+		logger: ctx.options.log(),
+		budget: ctx.options.memoryBudget,
+		dir:    ctx.options.spillDir,
+	}
+	err := ctx.mineFrequentItemsets(c, minSupport, s.add)
+	var result *SpilledItemsets
+	if err == nil {
+		result, err = s.finish(ctx.numTransactions)
+	}
+	if err != nil {
+		s.remove()
+		return nil, err
+	}
+	return result, nil
+}
+
+// finish merges the spiller's itemsets into a SpilledItemsets.
+func (s *spiller) finish(numTransactions int) (*SpilledItemsets, error) {
+	result := &SpilledItemsets{numTransactions: numTransactions, spiller: s}
+	if len(s.runs) == 0 {
+		s.sortBuffer()
+		result.itemsets = s.buffer
+		result.len = len(s.buffer)
+		result.support = createSupportLookup(s.buffer, numTransactions)
+		return result, nil
+	}
+	file, err := os.Create(filepath.Join(s.path, "itemsets"))
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(file)
+	buf := make([]byte, 0, 64)
+	err = s.merge(func(iwc ItemsetWithCount) error {
+		if result.len%spillIndexInterval == 0 {
+			result.index = append(result.index, spillIndexEntry{result.size, iwc.Itemset})
+		}
+		buf = appendItemsetRecord(buf[:0], iwc)
+		n, err := w.Write(buf)
+		result.size += int64(n)
+		result.len++
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	for _, run := range s.runs {
+		os.Remove(run)
+	}
+	s.runs = nil
+	result.file = file
+	result.blocks = make(map[int][]ItemsetWithCount)
+	return result, nil
+}
+
+// WriteSpilledItemsets writes itemsets to CSV file, in sorted order. Stops and
+// returns c.Err() if c is cancelled.
+func (ctx Context) WriteSpilledItemsets(
+	c context.Context,
+	itemsets *SpilledItemsets,
+	filePath string,
+) error {
+	output, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Itemset,Support")
+	n := float64(ctx.numTransactions)
+	err = itemsets.ForEach(func(iwc ItemsetWithCount) error {
+		if cancelled(c) {
+			return c.Err()
+		}
+		writeItemset(w, &ctx.itemizer, iwc.Itemset)
+		_, err := fmt.Fprintf(w, " %f\n", float64(iwc.Count)/n)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// ForEachRule generates the association rules of itemsets with confidence at
+// least minConfidence and lift at least minLift, calling fn with each in
+// turn rather than storing them, so the rules needn't fit in memory. Rules are
+// generated sequentially, in the order of their itemsets. Stops and returns
+// c.Err() if c is cancelled, fn's error if fn fails, or the error if reading
+// the itemsets fails.
+func (ctx Context) ForEachRule(
+	c context.Context,
+	itemsets *SpilledItemsets,
+	minConfidence float64,
+	minLift float64,
+	fn func(Rule) error,
+) error {
+	start := time.Now()
+	progress := startProgress(ctx.options.progress, PhaseRules, int64(itemsets.Len()))
+	defer progress.finish()
+	rules := make([]Rule, 0)
+	numRules := 0
+	err := itemsets.ForEach(func(iwc ItemsetWithCount) error {
+		if cancelled(c) {
+			return c.Err()
+		}
+		rules = appendItemsetRules(
+			rules[:0],
+			iwc,
+			ctx.numTransactions,
+			minConfidence,
+			minLift,
+			itemsets,
+		)
+		if itemsets.err != nil {
+			return itemsets.err
+		}
+		progress.add(1)
+		progress.addRules(len(rules))
+		numRules += len(rules)
+		for _, rule := range rules {
+			if err := fn(rule); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ctx.options.log().Debug(
+		"generated rules",
+		"phase", PhaseRules,
+		"itemsets", itemsets.Len(),
+		"rules", numRules,
+		"min_confidence", minConfidence,
+		"min_lift", minLift,
+		"duration", time.Since(start),
+	)
+	return nil
+}
+
+// WriteSpilledRules writes the association rules of itemsets generated by
+// ForEachRule() to a CSV file as they're generated, and returns the number of
+// rules written.
+func (ctx Context) WriteSpilledRules(
+	c context.Context,
+	itemsets *SpilledItemsets,
+	minConfidence float64,
+	minLift float64,
+	outputPath string,
+) (int, error) {
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Antecedent => Consequent,Confidence,Lift,Support")
+	numRules := 0
+	err = ctx.ForEachRule(c, itemsets, minConfidence, minLift, func(rule Rule) error {
+		numRules++
+		writeItemset(w, &ctx.itemizer, rule.Antecedent)
+		fmt.Fprint(w, " => ")
+		writeItemset(w, &ctx.itemizer, rule.Consequent)
+		_, err := fmt.Fprintf(
+			w,
+			",%f,%f,%f\n",
+			rule.Confidence,
+			rule.Lift,
+			rule.Support,
+		)
+		return err
+	})
+	if err != nil {
+		return numRules, err
+	}
+	return numRules, w.Flush()
+}
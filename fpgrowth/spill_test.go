@@ -0,0 +1,135 @@
+package fpgrowth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func sortedItemsets(itemsets []ItemsetWithCount) []ItemsetWithCount {
+	sorted := slices.Clone(itemsets)
+	slices.SortFunc(sorted, func(a, b ItemsetWithCount) int {
+		return compareItemsets(a.Itemset, b.Itemset)
+	})
+	return sorted
+}
+
+func sortedRules(rules []Rule) []Rule {
+	sorted := slices.Clone(rules)
+	slices.SortFunc(sorted, func(a, b Rule) int {
+		if c := compareItemsets(a.Antecedent, b.Antecedent); c != 0 {
+			return c
+		}
+		return compareItemsets(a.Consequent, b.Consequent)
+	})
+	return sorted
+}
+
+func TestSpilledItemsets(t *testing.T) {
+	path := writeTestCsv(t, randomTransactions(500, 9)...)
+	inMemory, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemsets, err := inMemory.GenerateItemsets(0.02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRules := flatten(generateRules(itemsets, inMemory.numTransactions, 0.1, 1, 1))
+
+	for _, budget := range []int64{0, 256} {
+		dir := t.TempDir()
+		ctx, err := Init(path, WithMemoryBudget(budget, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		spilled, err := ctx.GenerateSpilledItemsets(context.Background(), 0.02)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spilled.Spilled() != (budget > 0) {
+			t.Error("Budget ", budget, " expected spilled=", budget > 0)
+		}
+		if spilled.Len() != len(itemsets) {
+			t.Error("Expected ", len(itemsets), " itemsets, got ", spilled.Len())
+		}
+		got := make([]ItemsetWithCount, 0)
+		spilled.ForEach(func(iwc ItemsetWithCount) error {
+			got = append(got, iwc)
+			return nil
+		})
+		if !reflect.DeepEqual(got, sortedItemsets(itemsets)) {
+			t.Error("Budget ", budget, " generated different itemsets")
+		}
+
+		for _, iwc := range itemsets {
+			support, found := spilled.lookup(iwc.Itemset)
+			if !found || support != float64(iwc.Count)/float64(ctx.numTransactions) {
+				t.Error("Lookup of ", iwc.Itemset, " returned ", support, found)
+			}
+		}
+		for _, missing := range [][]Item{{}, {1 << 20}, {1, 1 << 20}} {
+			if _, found := spilled.lookup(missing); found {
+				t.Error("Lookup of ", missing, " unexpectedly found")
+			}
+		}
+
+		rules := make([]Rule, 0)
+		err = ctx.ForEachRule(context.Background(), spilled, 0.1, 1, func(r Rule) error {
+			rules = append(rules, r)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sortedRules(rules), sortedRules(expectedRules)) {
+			t.Error("Budget ", budget, " generated ", len(rules), " rules, expected ", len(expectedRules))
+		}
+
+		if err := spilled.Close(); err != nil {
+			t.Error(err)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Error("Close() left ", len(entries), " files in ", dir)
+		}
+	}
+}
+
+func TestSpilledItemsetsTruncated(t *testing.T) {
+	path := writeTestCsv(t, randomTransactions(500, 9)...)
+	ctx, err := Init(path, WithMemoryBudget(256, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spilled, err := ctx.GenerateSpilledItemsets(context.Background(), 0.02)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spilled.Close()
+	if len(spilled.index) < 2 {
+		t.Fatal("Expected several blocks, got ", len(spilled.index))
+	}
+	// Truncating at a block boundary leaves whole records, so the loss is
+	// only detected by counting them, or by looking up a lost itemset.
+	if err := spilled.file.Truncate(spilled.index[len(spilled.index)/2].offset); err != nil {
+		t.Fatal(err)
+	}
+	lost := spilled.index[len(spilled.index)-1].first
+	if _, found := spilled.lookup(lost); found || spilled.err == nil {
+		t.Error("Expected looking up a truncated itemset to fail")
+	}
+	spilled.err = nil
+	err = ctx.ForEachRule(context.Background(), spilled, 0.1, 1, func(Rule) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected ForEachRule to fail reading truncated itemsets")
+	}
+	output := filepath.Join(t.TempDir(), "rules.csv")
+	if _, err := ctx.WriteSpilledRules(context.Background(), spilled, 0.1, 1, output); err == nil {
+		t.Error("Expected WriteSpilledRules to fail reading truncated itemsets")
+	}
+}
@@ -0,0 +1,113 @@
+package fpgrowth
+
+import (
+	"cmp"
+	"slices"
+)
+
+// ItemSupport stores how often an item occurs in a dataset.
+type ItemSupport struct {
+	Item    string
+	Count   int
+	Support float64
+}
+
+// DatasetStats describes a Context's dataset, as counted by the first pass of
+// Init(), to help choose thresholds.
+type DatasetStats struct {
+	Transactions int
+	Items        int
+	// Lengths[n] is the number of transactions containing n items.
+	Lengths []int
+	// Density is the average number of items per transaction divided by the
+	// number of distinct items. Above the density threshold,
+	// GenerateItemsets() uses the Bitset miner.
+	Density float64
+	// ItemSupports stores the support of every item, in decreasing order of
+	// support, and then of first occurrence.
+	ItemSupports []ItemSupport
+}
+
+// SupportSurvivors describes the items which would be frequent at a minimum
+// support.
+type SupportSurvivors struct {
+	MinSupport float64
+	// Items is the number of items with support at least MinSupport.
+	Items int
+	// Density is the density of the dataset restricted to those items.
+	Density float64
+}
+
+// Stats returns statistics of the Context's dataset. Transaction lengths
+// aren't known for Contexts created by a StreamMiner.
+func (ctx Context) Stats() DatasetStats {
+	stats := DatasetStats{
+		Transactions: ctx.numTransactions,
+		Items:        ctx.NumItems(),
+		Lengths:      slices.Clone(ctx.lengths),
+		Density:      ctx.density(),
+		ItemSupports: make([]ItemSupport, 0, ctx.NumItems()),
+	}
+	for item := Item(1); int(item) <= ctx.itemizer.numItems; item++ {
+		name, found := ctx.itemizer.itemToStr[item]
+		if !found {
+			// Removed from a StreamMiner's window.
+			continue
+		}
+		count := ctx.frequency.get(item)
+		stats.ItemSupports = append(stats.ItemSupports, ItemSupport{
+			Item:    name,
+			Count:   count,
+			Support: float64(count) / float64(max(1, ctx.numTransactions)),
+		})
+	}
+	slices.SortStableFunc(stats.ItemSupports, func(a, b ItemSupport) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return stats
+}
+
+// Survivors returns the number of items which are frequent at each of
+// minSupports, and their density.
+func (ctx Context) Survivors(minSupports []float64) []SupportSurvivors {
+	survivors := make([]SupportSurvivors, len(minSupports))
+	for i, minSupport := range minSupports {
+		minCount := minCountFor(minSupport, ctx.numTransactions)
+		survivors[i] = SupportSurvivors{
+			MinSupport: minSupport,
+			Items:      len(frequentItems(&ctx, minCount)),
+			Density:    ctx.frequentDensity(minCount),
+		}
+	}
+	return survivors
+}
+
+// AverageLength returns the mean number of items per transaction.
+func (s DatasetStats) AverageLength() float64 {
+	total, n := 0, 0
+	for length, count := range s.Lengths {
+		total += length * count
+		n += count
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(total) / float64(n)
+}
+
+// LengthQuantile returns the smallest transaction length such that at least
+// fraction q of transactions are no longer.
+func (s DatasetStats) LengthQuantile(q float64) int {
+	n := 0
+	for _, count := range s.Lengths {
+		n += count
+	}
+	seen := 0
+	for length, count := range s.Lengths {
+		seen += count
+		if n > 0 && float64(seen) >= q*float64(n) {
+			return length
+		}
+	}
+	return max(0, len(s.Lengths)-1)
+}
@@ -0,0 +1,55 @@
+package fpgrowth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	path := writeTestCsv(t,
+		"a,b,c",
+		"a,b",
+		"a",
+		"a,d",
+		"b,c,d,e",
+	)
+	for _, parallelism := range []int{1, 4} {
+		ctx, err := Init(path, WithParallelism(parallelism))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats := ctx.Stats()
+		if stats.Transactions != 5 || stats.Items != 5 {
+			t.Error("Expected 5 transactions and items, got ", stats.Transactions, stats.Items)
+		}
+		if !reflect.DeepEqual(stats.Lengths, []int{0, 1, 2, 1, 1}) {
+			t.Error("Unexpected length distribution ", stats.Lengths)
+		}
+		if stats.AverageLength() != 12.0/5 || stats.Density != 12.0/5/5 {
+			t.Error("Unexpected average length ", stats.AverageLength(), " or density ", stats.Density)
+		}
+		if stats.LengthQuantile(0.5) != 2 || stats.LengthQuantile(1) != 4 {
+			t.Error("Unexpected median ", stats.LengthQuantile(0.5), " or maximum length")
+		}
+		expectedSupports := []ItemSupport{
+			{"a", 4, 0.8},
+			{"b", 3, 0.6},
+			{"c", 2, 0.4},
+			{"d", 2, 0.4},
+			{"e", 1, 0.2},
+		}
+		if !reflect.DeepEqual(stats.ItemSupports, expectedSupports) {
+			t.Error("Unexpected item supports ", stats.ItemSupports)
+		}
+
+		survivors := ctx.Survivors([]float64{0.5, 0.3, 0.1})
+		expectedSurvivors := []SupportSurvivors{
+			{0.5, 2, 7.0 / 5 / 2},
+			{0.3, 4, 11.0 / 5 / 4},
+			{0.1, 5, 12.0 / 5 / 5},
+		}
+		if !reflect.DeepEqual(survivors, expectedSurvivors) {
+			t.Error("Expected survivors ", expectedSurvivors, ", got ", survivors)
+		}
+	}
+}
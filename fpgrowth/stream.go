@@ -0,0 +1,178 @@
+package fpgrowth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StreamTransaction is a transaction received by a StreamMiner, along with
+// the time it occurred. A zero Time means the time it was received.
+type StreamTransaction struct {
+	Items []string
+	Time  time.Time
+}
+
+// StreamWindow bounds the transactions a StreamMiner considers. Transactions
+// are expired once there are more than Size newer transactions, or once
+// they're older than Duration before the latest transaction. Zero values
+// mean unbounded.
+type StreamWindow struct {
+	Size     int
+	Duration time.Duration
+}
+
+type windowedTransaction struct {
+	items []Item
+	count int
+	time  time.Time
+}
+
+// StreamMiner mines frequent itemsets over a sliding window of a stream of
+// transactions. It maintains an FP-tree of the transactions in the window,
+// inserting new transactions as they arrive and removing them as they expire.
+// Items are forgotten once no transaction in the window contains them, so
+// memory use is bounded by the window rather than the whole stream. It's safe
+// to add transactions and take snapshots concurrently.
+type StreamMiner struct {
+	mutex    sync.Mutex
+	window   StreamWindow
+	itemizer Itemizer
+	tree     *fpTree
+	// free stores the Items of expired items, for reuse by new items.
+	free []Item
+	// transactions stores the transactions in the window, oldest first.
+	transactions    []windowedTransaction
+	numTransactions int
+}
+
+// NewStreamMiner creates a StreamMiner over window.
+func NewStreamMiner(window StreamWindow) *StreamMiner {
+	return &StreamMiner{
+		window:   window,
+		itemizer: newItemizer(),
+		tree:     newTree(),
+	}
+}
+
+// Add adds a transaction which occurred at time at to the window, and expires
+// transactions which are no longer in the window. Transactions must be added
+// in order of time; returns an error if at is before the latest transaction.
+func (m *StreamMiner) Add(items []string, at time.Time) error {
+	return m.AddWeighted(items, 1, at)
+}
+
+// AddWeighted adds a transaction which occurred count times at time at to the
+// window. The transaction counts as one transaction for the window's Size.
+// Returns an error if count isn't positive, or at is before the latest
+// transaction.
+func (m *StreamMiner) AddWeighted(items []string, count int, at time.Time) error {
+	if count <= 0 {
+		return fmt.Errorf("transaction count %d isn't positive", count)
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if n := len(m.transactions); n > 0 && at.Before(m.transactions[n-1].time) {
+		return fmt.Errorf(
+			"transaction at %v is before the latest transaction at %v",
+			at,
+			m.transactions[n-1].time,
+		)
+	}
+
+	transaction := m.itemizer.itemizeReusing(items, &m.free)
+	sort.Slice(transaction, func(i, j int) bool {
+		return transaction[i] < transaction[j]
+	})
+	transaction = dedupeSorted(transaction)
+	m.tree.Insert(transaction, count)
+	m.transactions = append(m.transactions, windowedTransaction{
+		items: transaction,
+		count: count,
+		time:  at,
+	})
+	m.numTransactions += count
+	m.expire(at)
+	return nil
+}
+
+func (m *StreamMiner) expire(now time.Time) {
+	expired := 0
+	for _, t := range m.transactions {
+		tooMany := m.window.Size > 0 && len(m.transactions)-expired > m.window.Size
+		tooOld := m.window.Duration > 0 && now.Sub(t.time) > m.window.Duration
+		if !tooMany && !tooOld {
+			break
+		}
+		m.tree.Remove(t.items, t.count)
+		m.numTransactions -= t.count
+		for _, item := range t.items {
+			if m.tree.counts.get(item) == 0 {
+				m.itemizer.remove(item)
+				m.free = append(m.free, item)
+			}
+		}
+		expired++
+	}
+	if expired > 0 {
+		// Copy the remaining transactions, so the expired ones can be
+		// garbage collected.
+		m.transactions = append(
+			make([]windowedTransaction, 0, len(m.transactions)-expired),
+			m.transactions[expired:]...,
+		)
+	}
+}
+
+// Expire removes transactions older than the window's Duration before now.
+// Use this to expire transactions when none have arrived recently.
+func (m *StreamMiner) Expire(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.expire(now)
+}
+
+// Consume adds transactions received on the channel until it's closed.
+// Transactions which can't be added are skipped, and the errors adding them
+// are returned once the channel is closed.
+func (m *StreamMiner) Consume(transactions <-chan StreamTransaction) error {
+	var errs []error
+	for t := range transactions {
+		if err := m.Add(t.Items, t.Time); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Len returns the number of transactions in the window, counting weighted
+// transactions by their weight.
+func (m *StreamMiner) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.numTransactions
+}
+
+// Snapshot returns a Context of the transactions currently in the window,
+// which is independent of further changes to the window. Use its
+// GenerateItemsets() and GenerateRules() methods to query the current
+// frequent itemsets and rules, and its Write methods to output them. The
+// Context has no input files, so methods which read the dataset again, like
+// BuildClassifier(), see no transactions.
+func (m *StreamMiner) Snapshot() Context {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	tree := m.tree.clone()
+	return Context{
+		itemizer:        m.itemizer.clone(),
+		frequency:       tree.counts,
+		numTransactions: m.numTransactions,
+		options:         options{incremental: true},
+		tree:            tree,
+	}
+}
@@ -0,0 +1,152 @@
+package fpgrowth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamMinerWindowSize(t *testing.T) {
+	m := NewStreamMiner(StreamWindow{Size: 3})
+	for _, line := range []string{"a,b", "a,b", "c", "a,c", "a,c"} {
+		if err := m.Add(strings.Split(line, ","), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m.Len() != 3 {
+		t.Error("Expected 3 transactions in window, got ", m.Len())
+	}
+	if _, found := m.itemizer.strToItem["b"]; found || len(m.free) != 1 {
+		t.Error("Expired item b wasn't forgotten")
+	}
+	b := m.free[0]
+	if m.tree.head(b) != rootNode || m.tree.counts.get(b) != 0 {
+		t.Error("Expired item b still in tree")
+	}
+
+	ctx := m.Snapshot()
+	itemsets, err := ctx.GenerateItemsets(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"a": 2, "c": 3, "a c": 2}
+	if observed := itemsetCounts(ctx, itemsets); !equalCounts(observed, expected) {
+		t.Error("Expected itemsets ", expected, " got ", observed)
+	}
+	rules := ctx.GenerateRules(itemsets, 0.5, 0)
+	if len(rules) != 2 {
+		t.Error("Expected 2 rules, got ", rules)
+	}
+}
+
+func TestStreamMinerWindowDuration(t *testing.T) {
+	m := NewStreamMiner(StreamWindow{Duration: 90 * time.Second})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := make(chan StreamTransaction)
+	done := make(chan bool)
+	go func() {
+		if err := m.Consume(transactions); err != nil {
+			t.Error(err)
+		}
+		done <- true
+	}()
+	for i, line := range []string{"a", "a,b", "b"} {
+		transactions <- StreamTransaction{
+			Items: strings.Split(line, ","),
+			Time:  start.Add(time.Duration(i) * time.Minute),
+		}
+	}
+	close(transactions)
+	<-done
+	if m.Len() != 2 {
+		t.Error("Expected 2 transactions in window, got ", m.Len())
+	}
+	m.Expire(start.Add(4 * time.Minute))
+	if m.Len() != 0 {
+		t.Error("Expected empty window, got ", m.Len())
+	}
+}
+
+func TestStreamMinerMatchesBatch(t *testing.T) {
+	const windowSize = 100
+	lines := randomTransactions(400, 2)
+	m := NewStreamMiner(StreamWindow{Size: windowSize})
+	for i, line := range lines {
+		if err := m.Add(strings.Split(line, ","), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if (i+1)%75 != 0 {
+			continue
+		}
+		ctx := m.Snapshot()
+		batch, err := Init(writeTestCsv(t, lines[max(0, i+1-windowSize):i+1]...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, minSupport := range []float64{0.1, 0.3} {
+			observed, err := ctx.GenerateItemsets(minSupport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := batch.GenerateItemsets(minSupport)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := itemsetCounts(ctx, observed)
+			e := itemsetCounts(batch, expected)
+			if !equalCounts(o, e) {
+				t.Error("After ", i+1, " transactions, minSupport=", minSupport,
+					": window itemsets ", o, " don't match batch ", e)
+			}
+		}
+	}
+}
+
+func TestStreamMinerForgetsExpiredItems(t *testing.T) {
+	m := NewStreamMiner(StreamWindow{Size: 2})
+	for i := 0; i < 100; i++ {
+		items := []string{"common", fmt.Sprint("rare", i)}
+		if err := m.Add(items, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The window holds "common" and the two latest rare items, so at most
+	// four Items are ever needed.
+	if len(m.itemizer.strToItem) != 3 || m.itemizer.numItems > 4 || len(m.tree.heads) > 5 {
+		t.Error("Expected expired items to be forgotten, have ", m.itemizer.numItems, " items")
+	}
+	ctx := m.Snapshot()
+	if stats := ctx.Stats(); stats.Items != 3 || len(stats.ItemSupports) != 3 {
+		t.Error("Expected 3 items in the window, got ", stats.Items)
+	}
+	itemsets, err := ctx.GenerateItemsets(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{
+		"common": 2, "rare98": 1, "rare99": 1, "common rare98": 1, "common rare99": 1,
+	}
+	if observed := itemsetCounts(ctx, itemsets); !equalCounts(observed, expected) {
+		t.Error("Expected itemsets ", expected, " got ", observed)
+	}
+}
+
+func TestStreamMinerInvalidTransactions(t *testing.T) {
+	m := NewStreamMiner(StreamWindow{Duration: time.Minute})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := m.Add([]string{"a"}, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add([]string{"b"}, start); err == nil {
+		t.Error("Expected error adding a transaction out of order")
+	}
+	for _, count := range []int{0, -1} {
+		if err := m.AddWeighted([]string{"c"}, count, start.Add(time.Second)); err == nil {
+			t.Error("Expected error adding a transaction with count ", count)
+		}
+	}
+	if m.Len() != 1 || len(m.itemizer.strToItem) != 1 {
+		t.Error("Invalid transactions were added to the window")
+	}
+}
@@ -0,0 +1,191 @@
+package fpgrowth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// errBudgetExceeded stops mining once more itemsets than a budget allows have
+// been generated.
+var errBudgetExceeded = errors.New("itemset budget exceeded")
+
+// ItemsetBudget bounds the itemsets generated at the minimum support chosen by
+// SuggestMinSupport(). Zero fields are unbounded, but at least one must be
+// set.
+type ItemsetBudget struct {
+	// Itemsets is the maximum number of frequent itemsets.
+	Itemsets int
+	// Bytes is the maximum estimated size of the frequent itemsets in memory,
+	// as for WithMemoryBudget().
+	Bytes int64
+}
+
+// exceeded returns true if itemsets of total size bytes are beyond the budget.
+func (b ItemsetBudget) exceeded(itemsets int, bytes int64) bool {
+	return (b.Itemsets > 0 && itemsets > b.Itemsets) ||
+		(b.Bytes > 0 && bytes > b.Bytes)
+}
+
+// SupportSuggestion stores the minimum support chosen by SuggestMinSupport().
+type SupportSuggestion struct {
+	// MinSupport is the lowest minimum support found whose frequent itemsets
+	// are within the budget.
+	MinSupport float64
+	// Itemsets and Bytes are the number and estimated size of the frequent
+	// itemsets at MinSupport. If Sampled, they're estimated from the sample.
+	Itemsets int
+	Bytes    int64
+	// Probes is the number of minimum supports tried.
+	Probes int
+	// Sampled is true if the supports were tried on the sample collected by
+	// WithSample() rather than the whole dataset.
+	Sampled bool
+	// Complete is false if c expired before the search finished, in which case
+	// MinSupport is the lowest support tried that was within the budget.
+	Complete bool
+}
+
+// SuggestMinSupport estimates the lowest minimum support whose frequent
+// itemsets fit within budget, to pass to GenerateItemsets(). An FP-tree is
+// built once, of the items frequent enough that they alone don't exceed the
+// budget, and is then mined at a binary search of minimum counts. Mining stops
+// as soon as a count generates more itemsets than the budget allows, so each
+// probe does at most about a budget's worth of work.
+//
+// If the Context was created with WithSample(), the sample is mined rather
+// than the whole dataset, avoiding a pass over the dataset, and the result is
+// an estimate. To bound the time taken, pass a c with a deadline; once it
+// expires, the lowest support found within the budget so far is returned, or
+// an error wrapping c.Err() if none has been found.
+func (ctx Context) SuggestMinSupport(
+	c context.Context,
+	budget ItemsetBudget,
+) (SupportSuggestion, error) {
+	if budget.Itemsets <= 0 && budget.Bytes <= 0 {
+		return SupportSuggestion{}, errors.New("ItemsetBudget must limit the itemsets or their size")
+	}
+	counts, numTransactions, tree := ctx.frequency.counts, ctx.numTransactions, ctx.tree
+	suggestion := SupportSuggestion{}
+	if ctx.sample != nil {
+		tree, numTransactions = ctx.sample.tree()
+		counts = tree.counts.counts
+		suggestion.Sampled = true
+	}
+	if numTransactions == 0 {
+		return SupportSuggestion{}, errors.New("Context has no transactions")
+	}
+
+	// Each frequent item is itself a frequent itemset, so no count low enough
+	// that the frequent items exceed the budget need be tried.
+	lo := budgetMinCount(counts, budget)
+	hi := slices.Max(append([]int{0}, counts...)) + 1
+	if tree == nil {
+		var err error
+		tree, err = buildFrequentTree(
+			c,
+			ctx.inputCsvPaths,
+			ctx.options.weighted,
+			ctx.options.workers(),
+			ctx.options.progress,
+			lo,
+			&ctx.itemizer,
+			&ctx.frequency,
+		)
+		if err != nil {
+			return SupportSuggestion{}, err
+		}
+	}
+
+	// hi is the lowest count known to be within the budget. No item occurs
+	// hi times, so there are no itemsets at hi.
+	logger := ctx.options.log()
+	withinBudget := false
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start := time.Now()
+		itemsets, bytes := 0, int64(0)
+		err := mineTree(c, tree, mid, nil, nil, func(_ Item, x []ItemsetWithCount) error {
+			for _, iwc := range x {
+				itemsets++
+				bytes += itemsetSize(iwc)
+			}
+			if budget.exceeded(itemsets, bytes) {
+				return errBudgetExceeded
+			}
+			return nil
+		})
+		if err != nil && err != errBudgetExceeded {
+			if c.Err() == nil {
+				return SupportSuggestion{}, err
+			}
+			if !withinBudget {
+				return SupportSuggestion{}, fmt.Errorf("no min support within the budget found: %w", c.Err())
+			}
+			break
+		}
+		suggestion.Probes++
+		logger.Debug(
+			"probed min support",
+			"min_count", mid,
+			"itemsets", itemsets,
+			"within_budget", err == nil,
+			"duration", time.Since(start),
+		)
+		if err == errBudgetExceeded {
+			lo = mid + 1
+			continue
+		}
+		hi = mid
+		withinBudget = true
+		suggestion.Itemsets = itemsets
+		suggestion.Bytes = bytes
+	}
+	suggestion.Complete = lo >= hi
+	suggestion.MinSupport = supportFor(hi, numTransactions)
+	return suggestion, nil
+}
+
+// budgetMinCount returns the lowest minimum count at which the items with the
+// given counts, as singleton itemsets, are within budget.
+func budgetMinCount(counts []int, budget ItemsetBudget) int {
+	sorted := slices.Clone(counts)
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+	itemsets, bytes := 0, int64(0)
+	singleton := itemsetSize(ItemsetWithCount{Itemset: []Item{0}})
+	for _, count := range sorted {
+		if count == 0 {
+			break
+		}
+		itemsets++
+		bytes += singleton
+		if budget.exceeded(itemsets, bytes) {
+			return count + 1
+		}
+	}
+	return 1
+}
+
+// supportFor returns the minimum support whose minimum count is minCount.
+func supportFor(minCount int, numTransactions int) float64 {
+	support := float64(minCount) / float64(numTransactions)
+	if minCountFor(support, numTransactions) > minCount {
+		support = math.Nextafter(support, 0)
+	}
+	return support
+}
+
+// tree returns an FP-tree of the sampled transactions, and their total count.
+func (r *reservoir) tree() (*fpTree, int) {
+	tree := newTree()
+	total := 0
+	for _, t := range r.transactions {
+		total += t.count
+		tree.Insert(t.items, t.count)
+	}
+	return tree, total
+}
@@ -0,0 +1,98 @@
+package fpgrowth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestSuggestMinSupport(t *testing.T) {
+	path := writeTestCsv(t, randomTransactions(1000, 7)...)
+	ctx, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctx.SuggestMinSupport(context.Background(), ItemsetBudget{}); err == nil {
+		t.Error("Expected an unbounded budget to be rejected")
+	}
+	for _, budget := range []ItemsetBudget{{Itemsets: 5}, {Itemsets: 100}, {Bytes: 4096}} {
+		suggestion, err := ctx.SuggestMinSupport(context.Background(), budget)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !suggestion.Complete || suggestion.Sampled || suggestion.Probes == 0 {
+			t.Error("Unexpected search ", suggestion)
+		}
+		itemsets, err := ctx.GenerateItemsets(suggestion.MinSupport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bytes := int64(0)
+		for _, iwc := range itemsets {
+			bytes += itemsetSize(iwc)
+		}
+		if len(itemsets) != suggestion.Itemsets || bytes != suggestion.Bytes {
+			t.Error("Expected ", suggestion.Itemsets, " itemsets, got ", len(itemsets))
+		}
+		if budget.exceeded(len(itemsets), bytes) {
+			t.Error("Suggested support ", suggestion.MinSupport, " exceeds budget ", budget)
+		}
+		// The next lower count exceeds the budget.
+		minCount := minCountFor(suggestion.MinSupport, ctx.numTransactions)
+		lower, err := ctx.GenerateItemsets(float64(minCount-1) / float64(ctx.numTransactions))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bytes = 0
+		for _, iwc := range lower {
+			bytes += itemsetSize(iwc)
+		}
+		if minCount > 1 && !budget.exceeded(len(lower), bytes) {
+			t.Error("Expected support below ", suggestion.MinSupport, " to exceed budget ", budget)
+		}
+	}
+
+	sampled, err := Init(path, WithSample(200, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	suggestion, err := sampled.SuggestMinSupport(context.Background(), ItemsetBudget{Itemsets: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !suggestion.Sampled || suggestion.Itemsets > 100 || suggestion.MinSupport <= 0 {
+		t.Error("Unexpected sampled suggestion ", suggestion)
+	}
+}
+
+// cancelWriter cancels a context when a log record containing msg is written.
+type cancelWriter struct {
+	msg    string
+	cancel context.CancelFunc
+}
+
+func (w cancelWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte(w.msg)) {
+		w.cancel()
+	}
+	return len(p), nil
+}
+
+func TestSuggestMinSupportExpiresOverBudget(t *testing.T) {
+	// Every itemset of a b c d e f occurs 10 times, so every probe but the
+	// last exceeds a budget of 10 itemsets.
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := cancelWriter{msg: "probed min support", cancel: cancel}
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx, err := Init(writeTestCsv(t, repeatLines("a,b,c,d,e,f", 10)...), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	suggestion, err := ctx.SuggestMinSupport(c, ItemsetBudget{Itemsets: 10})
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected an error after no probe within budget, got ", suggestion, err)
+	}
+}
@@ -0,0 +1,192 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Option configures how a Context reads and analyzes its input.
+type Option func(*options)
+
+type options struct {
+	weighted    bool
+	incremental bool
+	sampleSize  int
+	sampleSeed  int64
+	// densityThreshold is the density above which GenerateItemsets() uses
+	// the Bitset miner.
+	densityThreshold float64
+	// parallelism is the number of goroutines used for parallel work.
+	parallelism int
+	progress    ProgressReporter
+	// memoryBudget is the approximate number of bytes of itemsets held in
+	// memory before they're spilled to disk in spillDir.
+	memoryBudget int64
+	spillDir     string
+	// checkpointDir is the directory mining progress is saved to, if any.
+	checkpointDir string
+	logger        *slog.Logger
+}
+
+const defaultDensityThreshold = 0.25
+
+func (o options) denseThreshold() float64 {
+	if o.densityThreshold == 0 {
+		return defaultDensityThreshold
+	}
+	return o.densityThreshold
+}
+
+// WithWeightedTransactions treats the first column of every input line as an
+// integer count of how many times that transaction occurs. For example the
+// line "3,bread,milk" is equivalent to three lines of "bread,milk". This
+// allows pre-aggregated datasets to be analyzed without expanding duplicate
+// baskets, and produces identical results to the expanded dataset.
+func WithWeightedTransactions() Option {
+	return func(o *options) {
+		o.weighted = true
+	}
+}
+
+// WithIncrementalMining retains an FP-tree of every transaction in the
+// Context, so that batches of transactions can be added with AddTransactions()
+// and itemsets regenerated without reading the whole dataset again. The tree
+// stores all items regardless of their support, so this uses more memory
+// than the default of building a tree of only frequent items on demand.
+func WithIncrementalMining() Option {
+	return func(o *options) {
+		o.incremental = true
+	}
+}
+
+// WithSample collects a uniform random sample of size transactions during the
+// first pass over the dataset, which GenerateItemsetsFromSample() mines. The
+// sample is chosen by reservoir sampling using a random number generator
+// seeded with seed, so the same seed gives the same sample.
+func WithSample(size int, seed int64) Option {
+	return func(o *options) {
+		o.sampleSize = size
+		o.sampleSeed = seed
+	}
+}
+
+// WithDensityThreshold sets the density of the dataset above which
+// GenerateItemsets() mines with the Bitset miner rather than FP-growth. Density
+// is the average number of items per transaction divided by the number of
+// distinct items. Defaults to 0.25. Pass a threshold above 1 to
+// always use FP-growth. Weighted datasets always use FP-growth.
+func WithDensityThreshold(threshold float64) Option {
+	return func(o *options) {
+		o.densityThreshold = threshold
+	}
+}
+
+// WithParallelism sets the number of goroutines used to read the input and
+// generate rules. Defaults to GOMAXPROCS. Large input files are split into
+// chunks of whole lines, which are read in parallel both when counting items
+// and when building the FP-tree. Results are the same regardless of
+// parallelism. Inputs are read sequentially when mining incrementally or
+// sampling.
+func WithParallelism(n int) Option {
+	return func(o *options) {
+		o.parallelism = n
+	}
+}
+
+func (o options) workers() int {
+	if o.parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.parallelism
+}
+
+// WithProgress reports the progress of each phase of the analysis to
+// reporter. By default progress isn't reported.
+func WithProgress(reporter ProgressReporter) Option {
+	return func(o *options) {
+		o.progress = reporter
+	}
+}
+
+func makeOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// parseTransaction splits a line of input into its item tokens, and the
+// number of times the transaction occurs. Blank weighted lines have a count
+// of zero, so are skipped.
+func parseTransaction(line string, weighted bool) ([]string, int, error) {
+	tokens := strings.Split(line, ",")
+	if !weighted {
+		return tokens, 1, nil
+	}
+	if len(strings.TrimSpace(line)) == 0 {
+		return nil, 0, nil
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(tokens[0]))
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid transaction count %q", tokens[0])
+	}
+	if count < 0 {
+		return nil, 0, fmt.Errorf("negative transaction count %d", count)
+	}
+	return tokens[1:], count, nil
+}
+
+// forEachTransaction reads the transactions in the CSV file at path, and
+// calls fn with the tokens of each, along with the number of times that
+// transaction occurs. Transactions with a count of zero are skipped. Stops
+// and returns the error if fn fails.
+func forEachTransaction(
+	path string,
+	weighted bool,
+	fn func(tokens []string, count int) error,
+) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	line, err := scanTransactions(file, weighted, fn)
+	if err != nil && line > 0 {
+		return fmt.Errorf("%s:%d: %w", path, line, err)
+	}
+	return err
+}
+
+// scanTransactions reads the transactions in r, and calls fn with each as
+// forEachTransaction does. If parsing a line or fn fails, returns the line's
+// number along with the error.
+func scanTransactions(
+	r io.Reader,
+	weighted bool,
+	fn func(tokens []string, count int) error,
+) (int, error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		tokens, count, err := parseTransaction(scanner.Text(), weighted)
+		if err != nil {
+			return line, err
+		}
+		if count == 0 {
+			continue
+		}
+		if err := fn(tokens, count); err != nil {
+			return line, err
+		}
+	}
+	return 0, scanner.Err()
+}
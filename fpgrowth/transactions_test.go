@@ -0,0 +1,128 @@
+package fpgrowth
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeTestCsv(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// itemsetCounts maps the human readable form of each itemset to its count, so
+// that itemsets can be compared across Contexts with different Itemizers.
+func itemsetCounts(ctx Context, itemsets GeneratedItemsets) map[string]int {
+	counts := make(map[string]int)
+	for _, iwc := range itemsets {
+		names := make([]string, 0, len(iwc.Itemset))
+		for _, item := range iwc.Itemset {
+			names = append(names, ctx.itemizer.ToStr(item))
+		}
+		sort.Strings(names)
+		counts[strings.Join(names, " ")] = iwc.Count
+	}
+	return counts
+}
+
+func equalCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, found := b[k]; !found || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWeightedTransactions(t *testing.T) {
+	expanded := writeTestCsv(t,
+		"a,b,c",
+		"a,b,c",
+		"a,b,c",
+		"a,d",
+		"b,c,d",
+		"b,c,d",
+		"e",
+	)
+	weighted := writeTestCsv(t,
+		"3,a,b,c",
+		"1,a,d",
+		"2,b,c,d",
+		"0,a,e",
+		"1,e",
+	)
+
+	expandedCtx, err := Init(expanded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weightedCtx, err := Init(weighted, WithWeightedTransactions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expandedCtx.numTransactions != 7 || weightedCtx.numTransactions != 7 {
+		t.Error(
+			"Expected 7 transactions, got ",
+			expandedCtx.numTransactions,
+			" and ",
+			weightedCtx.numTransactions,
+		)
+	}
+
+	expandedItemsets, err := expandedCtx.GenerateItemsets(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weightedItemsets, err := weightedCtx.GenerateItemsets(0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := itemsetCounts(expandedCtx, expandedItemsets)
+	w := itemsetCounts(weightedCtx, weightedItemsets)
+	if !equalCounts(e, w) {
+		t.Error("Weighted itemsets ", w, " don't match expanded ", e)
+	}
+	if e["a b c"] != 3 || e["b c d"] != 2 {
+		t.Error("Unexpected itemset counts ", e)
+	}
+}
+
+func TestWeightedTransactionsBlankLines(t *testing.T) {
+	path := writeTestCsv(t, "2,a,b", "", "1,a", " ", "")
+	for _, parallelism := range []int{1, 4} {
+		ctx, err := Init(path, WithWeightedTransactions(), WithParallelism(parallelism))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ctx.numTransactions != 3 {
+			t.Error("Expected 3 transactions, got ", ctx.numTransactions)
+		}
+		itemsets, err := ctx.GenerateItemsets(0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := itemsetCounts(ctx, itemsets); c["a"] != 3 || c["a b"] != 2 {
+			t.Error("Unexpected itemset counts ", c)
+		}
+	}
+}
+
+func TestWeightedTransactionsInvalidCount(t *testing.T) {
+	for _, line := range []string{"x,a,b", "-1,a,b"} {
+		path := writeTestCsv(t, "1,a", line)
+		if _, err := Init(path, WithWeightedTransactions()); err == nil {
+			t.Error("Expected error parsing ", line)
+		}
+	}
+}
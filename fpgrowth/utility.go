@@ -0,0 +1,365 @@
+package fpgrowth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ItemsetWithUtility wraps a high utility itemset with its total utility
+// across all transactions, and the number of transactions it occurs in.
+type ItemsetWithUtility struct {
+	Itemset []Item
+	Utility float64
+	Count   int
+}
+
+// UtilityContext stores context for a high utility itemset analysis.
+// Transactions list the quantity of each item purchased, and a separate
+// table stores the unit profit of each item. The utility of an itemset in a
+// transaction is the sum of quantity times unit profit of its items, and the
+// utility of an itemset is the sum of its utilities in the transactions which
+// contain it.
+type UtilityContext struct {
+	inputCsvPath    string
+	itemizer        Itemizer
+	profits         []float64
+	twu             []float64
+	numTransactions int
+	totalUtility    float64
+}
+
+type itemQuantity struct {
+	item     Item
+	quantity int
+}
+
+// InitUtility creates a UtilityContext. The unit profit table at profitsPath
+// is a CSV file with lines of the form "item,profit". Each transaction in
+// inputCsvPath is a line of comma separated "item:quantity" pairs; items
+// without an explicit quantity have quantity 1. Performs a first pass on the
+// dataset computing the transaction weighted utility of every item.
+func InitUtility(inputCsvPath string, profitsPath string) (UtilityContext, error) {
+	itemizer := newItemizer()
+	profits, err := readProfits(profitsPath, &itemizer)
+	if err != nil {
+		return UtilityContext{}, err
+	}
+	ctx := UtilityContext{
+		inputCsvPath: inputCsvPath,
+		itemizer:     itemizer,
+		profits:      profits,
+		twu:          make([]float64, len(profits)),
+	}
+	err = ctx.forEachTransaction(func(transaction []itemQuantity) {
+		tu := ctx.transactionUtility(transaction)
+		for _, iq := range transaction {
+			ctx.twu[iq.item] += tu
+		}
+		ctx.totalUtility += tu
+		ctx.numTransactions++
+	})
+	if err != nil {
+		return UtilityContext{}, err
+	}
+	return ctx, nil
+}
+
+// TotalUtility returns the sum of the utilities of all transactions.
+func (ctx UtilityContext) TotalUtility() float64 {
+	return ctx.totalUtility
+}
+
+func readProfits(path string, itemizer *Itemizer) ([]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Items are numbered from 1, so reserve index 0 for invalidItem.
+	profits := []float64{0}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected item,profit", path, line)
+		}
+		profit, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid profit %q", path, line, fields[1])
+		}
+		if profit < 0 {
+			// Negative profits would break the transaction weighted
+			// utility's upper bound on the utility of supersets.
+			return nil, fmt.Errorf("%s:%d: negative profit %q", path, line, fields[1])
+		}
+		items := itemizer.Itemize(fields[:1])
+		if len(items) != 1 {
+			return nil, fmt.Errorf("%s:%d: missing item name", path, line)
+		}
+		profits = ensureInBoundsFloat(profits, int(items[0]))
+		profits[items[0]] = profit
+	}
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+	return profits, nil
+}
+
+func ensureInBoundsFloat(slice []float64, index int) []float64 {
+	if index < len(slice) {
+		return slice
+	}
+	delta := 1 + index - len(slice)
+	return append(slice, make([]float64, delta)...)
+}
+
+// parseTransaction parses the item:quantity pairs of a transaction. The
+// quantities of an item listed more than once are summed, so each item occurs
+// in the transaction once.
+func (ctx *UtilityContext) parseTransaction(tokens []string) ([]itemQuantity, error) {
+	transaction := make([]itemQuantity, 0, len(tokens))
+	for _, token := range tokens {
+		name, quantity := strings.TrimSpace(token), 1
+		if idx := strings.LastIndexByte(name, ':'); idx >= 0 {
+			q, err := strconv.Atoi(strings.TrimSpace(name[idx+1:]))
+			if err != nil || q < 0 {
+				return nil, fmt.Errorf("invalid quantity in %q", token)
+			}
+			name, quantity = strings.TrimSpace(name[:idx]), q
+		}
+		if len(name) == 0 || quantity == 0 {
+			continue
+		}
+		item, found := ctx.itemizer.strToItem[name]
+		if !found {
+			return nil, fmt.Errorf("no unit profit for item %q", name)
+		}
+		duplicate := false
+		for i := range transaction {
+			if transaction[i].item == item {
+				transaction[i].quantity += quantity
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			transaction = append(transaction, itemQuantity{item, quantity})
+		}
+	}
+	return transaction, nil
+}
+
+func (ctx *UtilityContext) forEachTransaction(fn func([]itemQuantity)) error {
+	return forEachTransaction(
+		ctx.inputCsvPath,
+		false,
+		func(tokens []string, _ int) error {
+			transaction, err := ctx.parseTransaction(tokens)
+			if err != nil {
+				return err
+			}
+			fn(transaction)
+			return nil
+		},
+	)
+}
+
+func (ctx *UtilityContext) utility(iq itemQuantity) float64 {
+	return float64(iq.quantity) * ctx.profits[iq.item]
+}
+
+func (ctx *UtilityContext) transactionUtility(transaction []itemQuantity) float64 {
+	tu := 0.0
+	for _, iq := range transaction {
+		tu += ctx.utility(iq)
+	}
+	return tu
+}
+
+type utilityEntry struct {
+	tid       int
+	utility   float64
+	remaining float64
+}
+
+// utilityList stores, for each transaction containing an itemset, the utility
+// of the itemset in that transaction and the utility of the items which come
+// after the itemset in the processing order. Entries are sorted by tid.
+type utilityList struct {
+	item      Item
+	entries   []utilityEntry
+	utility   float64
+	remaining float64
+}
+
+func (ul *utilityList) add(e utilityEntry) {
+	ul.entries = append(ul.entries, e)
+	ul.utility += e.utility
+	ul.remaining += e.remaining
+}
+
+func (ul *utilityList) find(tid int) (utilityEntry, bool) {
+	idx := sort.Search(len(ul.entries), func(i int) bool {
+		return ul.entries[i].tid >= tid
+	})
+	if idx < len(ul.entries) && ul.entries[idx].tid == tid {
+		return ul.entries[idx], true
+	}
+	return utilityEntry{}, false
+}
+
+type itemPair struct {
+	a, b Item
+}
+
+// GenerateHighUtilityItemsets generates the itemsets whose utility is at
+// least minUtility. Uses the FHM algorithm; items whose transaction weighted
+// utility is below minUtility can't be part of any high utility itemset so are
+// pruned, and co-occurrence of pairs of items is used to prune extensions
+// before their utility lists are constructed.
+func (ctx UtilityContext) GenerateHighUtilityItemsets(
+	minUtility float64,
+) ([]ItemsetWithUtility, error) {
+	// Process items in order of increasing TWU, tie break on item.
+	order := make([]int, len(ctx.twu))
+	promising := make([]Item, 0)
+	for i := 1; i < len(ctx.twu); i++ {
+		if ctx.twu[i] >= minUtility && ctx.twu[i] > 0 {
+			promising = append(promising, Item(i))
+		}
+	}
+	sort.SliceStable(promising, func(i, j int) bool {
+		return ctx.twu[promising[i]] < ctx.twu[promising[j]]
+	})
+	lists := make([]*utilityList, len(promising))
+	for rank, item := range promising {
+		order[item] = rank + 1
+		lists[rank] = &utilityList{item: item}
+	}
+
+	cooccurrence := make(map[itemPair]float64)
+	tid := 0
+	err := ctx.forEachTransaction(func(transaction []itemQuantity) {
+		revised := make([]itemQuantity, 0, len(transaction))
+		for _, iq := range transaction {
+			if order[iq.item] > 0 {
+				revised = append(revised, iq)
+			}
+		}
+		sort.Slice(revised, func(i, j int) bool {
+			return order[revised[i].item] < order[revised[j].item]
+		})
+		tu := ctx.transactionUtility(revised)
+		remaining := tu
+		for i, iq := range revised {
+			u := ctx.utility(iq)
+			remaining -= u
+			lists[order[iq.item]-1].add(utilityEntry{tid, u, remaining})
+			for _, other := range revised[i+1:] {
+				cooccurrence[itemPair{iq.item, other.item}] += tu
+			}
+		}
+		tid++
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	miner := utilityMiner{
+		minUtility:   minUtility,
+		cooccurrence: cooccurrence,
+		itemsets:     make([]ItemsetWithUtility, 0),
+	}
+	miner.search(nil, nil, lists)
+	return miner.itemsets, nil
+}
+
+type utilityMiner struct {
+	minUtility   float64
+	cooccurrence map[itemPair]float64
+	itemsets     []ItemsetWithUtility
+}
+
+func (m *utilityMiner) search(
+	prefix []Item,
+	prefixList *utilityList,
+	lists []*utilityList,
+) {
+	for i, x := range lists {
+		itemset := appendSorted(prefix, x.item)
+		if x.utility >= m.minUtility {
+			m.itemsets = append(m.itemsets, ItemsetWithUtility{
+				Itemset: itemset,
+				Utility: x.utility,
+				Count:   len(x.entries),
+			})
+		}
+		if x.utility+x.remaining < m.minUtility {
+			continue
+		}
+		extensions := make([]*utilityList, 0, len(lists)-i-1)
+		for _, y := range lists[i+1:] {
+			if m.cooccurrence[itemPair{x.item, y.item}] < m.minUtility {
+				continue
+			}
+			if xy := m.construct(prefixList, x, y); xy != nil {
+				extensions = append(extensions, xy)
+			}
+		}
+		m.search(itemset, x, extensions)
+	}
+}
+
+// construct builds the utility list of prefix+x+y from the utility lists of
+// prefix, prefix+x, and prefix+y. Returns nil if prefix+x+y and all its
+// extensions can't be high utility.
+func (m *utilityMiner) construct(p, px, py *utilityList) *utilityList {
+	pxy := &utilityList{item: py.item}
+	bound := px.utility + px.remaining
+	for _, ex := range px.entries {
+		ey, found := py.find(ex.tid)
+		if !found {
+			bound -= ex.utility + ex.remaining
+			if bound < m.minUtility {
+				return nil
+			}
+			continue
+		}
+		u := ex.utility + ey.utility
+		if p != nil {
+			e, _ := p.find(ex.tid)
+			u -= e.utility
+		}
+		pxy.add(utilityEntry{ex.tid, u, ey.remaining})
+	}
+	return pxy
+}
+
+// WriteHighUtilityItemsets writes high utility itemsets to CSV file.
+func (ctx UtilityContext) WriteHighUtilityItemsets(
+	itemsets []ItemsetWithUtility,
+	filePath string,
+) error {
+	output, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	w := bufio.NewWriter(output)
+	fmt.Fprintln(w, "Itemset,Utility,Count")
+	for _, iwu := range itemsets {
+		writeItemset(w, &ctx.itemizer, iwu.Itemset)
+		fmt.Fprintf(w, ",%f,%d\n", iwu.Utility, iwu.Count)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,137 @@
+package fpgrowth
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bruteForceUtilities computes the utility of every itemset that occurs in
+// the transactions by enumerating each transaction's subsets.
+func bruteForceUtilities(ctx UtilityContext) map[string]float64 {
+	utilities := make(map[string]float64)
+	ctx.forEachTransaction(func(transaction []itemQuantity) {
+		n := uint(len(transaction))
+		for mask := 1; mask < 1<<n; mask++ {
+			itemset := make([]Item, 0)
+			u := 0.0
+			for i := uint(0); i < n; i++ {
+				if mask&(1<<i) != 0 {
+					itemset = appendSorted(itemset, transaction[i].item)
+					u += ctx.utility(transaction[i])
+				}
+			}
+			utilities[itemsetKey(itemset)] += u
+		}
+	})
+	return utilities
+}
+
+func itemsetKey(itemset []Item) string {
+	key := ""
+	for _, item := range itemset {
+		key += string(rune('A'+int(item))) + " "
+	}
+	return key
+}
+
+func TestHighUtilityItemsets(t *testing.T) {
+	input := writeTestCsv(t,
+		"a:1,c:1,d:1",
+		"a:2,c:6,e:2,g:5",
+		"a:1,b:2,c:1,d:6,e:1,f:5",
+		"b:4,c:3,d:3,e:1",
+		"b:2,c:2,e:1,g:2",
+	)
+	profits := filepath.Join(t.TempDir(), "profits.csv")
+	err := os.WriteFile(profits, []byte("a,5\nb,2\nc,1\nd,2\ne,3\nf,1\ng,1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := InitUtility(input, profits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.TotalUtility() != 96 {
+		t.Error("Expected total utility 96, got ", ctx.TotalUtility())
+	}
+
+	expected := bruteForceUtilities(ctx)
+	for _, minUtility := range []float64{1, 25, 30, 40} {
+		itemsets, err := ctx.GenerateHighUtilityItemsets(minUtility)
+		if err != nil {
+			t.Fatal(err)
+		}
+		observed := make(map[string]float64)
+		for _, iwu := range itemsets {
+			observed[itemsetKey(iwu.Itemset)] = iwu.Utility
+		}
+		for key, u := range expected {
+			if u < minUtility {
+				continue
+			}
+			if v, found := observed[key]; !found || math.Abs(u-v) > 1e-9 {
+				t.Error("minUtility ", minUtility, ": itemset ", key,
+					" expected utility ", u, " got ", v)
+			}
+			delete(observed, key)
+		}
+		if len(observed) != 0 {
+			t.Error("minUtility ", minUtility, ": unexpected itemsets ", observed)
+		}
+	}
+}
+
+func TestHighUtilityUnknownItem(t *testing.T) {
+	input := writeTestCsv(t, "a:1,z:2")
+	profits := filepath.Join(t.TempDir(), "profits.csv")
+	if err := os.WriteFile(profits, []byte("a,5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitUtility(input, profits); err == nil {
+		t.Error("Expected error for item without unit profit")
+	}
+}
+
+func TestHighUtilityDuplicateItems(t *testing.T) {
+	input := writeTestCsv(t,
+		"a:1,b:2,a:2",
+		"a,b:1",
+	)
+	profits := filepath.Join(t.TempDir(), "profits.csv")
+	if err := os.WriteFile(profits, []byte("a,5\nb,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := InitUtility(input, profits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.TotalUtility() != 26 {
+		t.Error("Expected total utility 26, got ", ctx.TotalUtility())
+	}
+	itemsets, err := ctx.GenerateHighUtilityItemsets(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]float64{"B ": 20, "C ": 6, "B C ": 26}
+	if len(itemsets) != len(expected) {
+		t.Error("Expected ", len(expected), " itemsets, got ", itemsets)
+	}
+	for _, iwu := range itemsets {
+		if u := expected[itemsetKey(iwu.Itemset)]; u != iwu.Utility || iwu.Count != 2 {
+			t.Error("Unexpected utility ", iwu.Utility, " or count of ", iwu.Itemset)
+		}
+	}
+}
+
+func TestHighUtilityNegativeProfit(t *testing.T) {
+	input := writeTestCsv(t, "a:1,b:2")
+	profits := filepath.Join(t.TempDir(), "profits.csv")
+	if err := os.WriteFile(profits, []byte("a,5\nb,-2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InitUtility(input, profits); err == nil {
+		t.Error("Expected error for negative unit profit")
+	}
+}